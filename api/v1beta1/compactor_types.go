@@ -0,0 +1,185 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// CompactorSpec defines the desired state of Compactor
+type CompactorSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Standard object’s metadata. More info:
+	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md
+	// Metadata Labels and Annotations gets propagated to the compactor pods.
+	PodMetadata *metav1.ObjectMeta `json:"podMetadata,omitempty"`
+	// ServiceMetadata Labels and Annotations gets propagated to the generated Service.
+	ServiceMetadata *metav1.ObjectMeta `json:"serviceMetadata,omitempty"`
+
+	// Define resources requests and limits for single Pods.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Probes overrides the timings of the liveness and readiness probes.
+	Probes *Probes `json:"probes,omitempty"`
+
+	// object storage type, only GCS is supported. Configure the other
+	// providers with objectStorage.
+	// Deprecated: use ObjectStorageConfig instead.
+	ObjectStorageType string `json:"objstoreType,omitempty"`
+
+	// secret name is gcs iam secret name
+	// Deprecated: use ObjectStorageConfig instead.
+	SecretName string `json:"secretName,omitempty"`
+
+	// object storage bucket name need set object storage type
+	// Deprecated: use ObjectStorageConfig instead.
+	BucketName string `json:"bucketName,omitempty"`
+
+	// Define which Nodes the Pods are scheduled on.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// If specified, the pod's scheduling constraints. Topology spread
+	// constraints are not supported: the Kubernetes API the operator is built
+	// against (1.14) predates them, spread the pods with podAntiAffinity.
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// If specified, the pod's tolerations.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Priority class assigned to the Pods
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// SecurityContext holds pod-level security attributes and common container settings.
+	// This defaults to the default PodSecurityContext.
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// ObjectStorageConfig configures object storage in Thanos.
+	// The referenced secret key holds a complete thanos objstore configuration
+	// and takes precedence over objstoreType, secretName and bucketName.
+	ObjectStorageConfig *corev1.SecretKeySelector `json:"objectStorageConfig,omitempty"`
+
+	// ObjectStorage configures object storage in Thanos from typed fields.
+	// The operator renders it into a generated Secret. It is ignored when
+	// objectStorageConfig is set.
+	ObjectStorage *ObjectStorage `json:"objectStorage,omitempty"`
+
+	// DataDir is the working directory for compaction and downsampling
+	DataDir string `json:"dataDir,omitempty"`
+
+	// Storage is the size of the persistent volume backing DataDir (e.g. 10Gi)
+	Storage string `json:"storage,omitempty"`
+
+	// RetentionResolutionRaw is how long to retain raw samples in the bucket.
+	// 0d means keep forever.
+	RetentionResolutionRaw string `json:"retentionResolutionRaw,omitempty"`
+
+	// RetentionResolution5m is how long to retain samples of resolution 5m in the bucket.
+	// 0d means keep forever.
+	RetentionResolution5m string `json:"retentionResolution5m,omitempty"`
+
+	// RetentionResolution1h is how long to retain samples of resolution 1h in the bucket.
+	// 0d means keep forever.
+	RetentionResolution1h string `json:"retentionResolution1h,omitempty"`
+
+	// DisableDownsampling disables downsampling of compacted blocks.
+	DisableDownsampling bool `json:"disableDownsampling,omitempty"`
+
+	// Image if specified has precedence over baseImage, tag and sha
+	// combinations. Specifying the version is still necessary to ensure the
+	// Thanos Operator knows what version of Thanos is being
+	// configured.
+	Image *string `json:"image,omitempty"`
+
+	// Log level for Thanos to be configured with.
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// Secrets is a list of Secrets in the same namespace as the Compactor
+	// object, which shall be mounted into the Compactor Pods.
+	// The Secrets are mounted into /etc/thanos/user-secrets/<secret-name>.
+	Secrets []string `json:"secrets,omitempty"`
+
+	// Volumes allows configuration of additional volumes on the generated
+	// pods. Volumes specified will be appended to the generated ones.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+
+	// VolumeMounts allows configuration of additional VolumeMounts on the
+	// compactor container. VolumeMounts specified will be appended to the
+	// generated ones.
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// InitContainers allows adding initContainers to the pod definition.
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+
+	// Containers allows injecting additional containers or modifying
+	// operator generated containers. Containers are merged with the
+	// generated ones by name using a strategic merge patch; the thanos
+	// container is named compactor.
+	Containers []corev1.Container `json:"containers,omitempty"`
+}
+
+// CompactorStatus defines the observed state of Compactor
+type CompactorStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// statefulSetStatus contains the status of the StatefulSet managed by Thanos
+	StatefulSetStatus appsv1.StatefulSetStatus `json:"statefulSetStatus,omitempty"`
+
+	// serviceStatus contains the status of the Service managed by thanos compactor
+	ServiceStatus corev1.ServiceStatus `json:"serviceStatus,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled successfully.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the current state of the compactor.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:printcolumn:name="storage",type="string",JSONPath=".spec.storage",format="byte"
+// +kubebuilder:printcolumn:name="ready replicas",type="integer",JSONPath=".status.statefulSetStatus.readyReplicas",format="int32"
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Compactor is the Schema for the compactors API
+type Compactor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CompactorSpec   `json:"spec,omitempty"`
+	Status CompactorStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CompactorList contains a list of Compactor
+type CompactorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Compactor `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Compactor{}, &CompactorList{})
+}
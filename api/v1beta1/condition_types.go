@@ -0,0 +1,61 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType is the type of a Condition
+type ConditionType string
+
+const (
+	// ConditionAvailable means every desired replica is available.
+	ConditionAvailable ConditionType = "Available"
+	// ConditionProgressing means a rollout is in progress.
+	ConditionProgressing ConditionType = "Progressing"
+	// ConditionDegraded means replicas are unavailable while no rollout is
+	// in progress.
+	ConditionDegraded ConditionType = "Degraded"
+	// ConditionReconcileError means the last reconciliation failed.
+	ConditionReconcileError ConditionType = "ReconcileError"
+	// ConditionStorageResizing means persistent volumes are being expanded
+	// to a new storage size.
+	ConditionStorageResizing ConditionType = "StorageResizing"
+)
+
+// Condition describes one aspect of the state of a Thanos component
+type Condition struct {
+	// Type of the condition.
+	Type ConditionType `json:"type"`
+
+	// Status of the condition, one of True, False or Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+
+	// ObservedGeneration is the generation the condition was computed for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastTransitionTime is the last time the status changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a CamelCase reason for the last transition.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the last transition.
+	Message string `json:"message,omitempty"`
+}
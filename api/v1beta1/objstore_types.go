@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ObjectStorage describes the bucket Thanos components read blocks from and
+// write blocks to. Exactly one of the provider fields must be set.
+type ObjectStorage struct {
+	// GCS configures a Google Cloud Storage bucket.
+	GCS *GCSObjectStorage `json:"gcs,omitempty"`
+
+	// S3 configures an S3 compatible bucket.
+	S3 *S3ObjectStorage `json:"s3,omitempty"`
+
+	// Azure configures an Azure Blob Storage container.
+	Azure *AzureObjectStorage `json:"azure,omitempty"`
+
+	// Filesystem configures a local directory. Only meant for testing.
+	Filesystem *FilesystemObjectStorage `json:"filesystem,omitempty"`
+}
+
+// GCSObjectStorage configures a Google Cloud Storage bucket
+type GCSObjectStorage struct {
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+
+	// ServiceAccount selects the JSON key of the service account used to
+	// access the bucket. Defaults to the credentials of the node.
+	ServiceAccount *corev1.SecretKeySelector `json:"serviceAccount,omitempty"`
+}
+
+// S3ObjectStorage configures an S3 compatible bucket
+type S3ObjectStorage struct {
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+
+	// Endpoint is the S3 endpoint, e.g. s3.eu-west-1.amazonaws.com.
+	Endpoint string `json:"endpoint"`
+
+	// Region is the S3 region, only needed by some providers.
+	Region string `json:"region,omitempty"`
+
+	// AccessKey selects the access key id.
+	AccessKey *corev1.SecretKeySelector `json:"accessKey,omitempty"`
+
+	// SecretKey selects the secret access key.
+	SecretKey *corev1.SecretKeySelector `json:"secretKey,omitempty"`
+
+	// Insecure talks to the endpoint over plain HTTP.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// SignatureVersion2 signs requests with AWS signature version 2.
+	SignatureVersion2 bool `json:"signatureVersion2,omitempty"`
+
+	// EncryptSSE enables server side encryption of uploaded objects.
+	EncryptSSE bool `json:"encryptSSE,omitempty"`
+}
+
+// AzureObjectStorage configures an Azure Blob Storage container
+type AzureObjectStorage struct {
+	// StorageAccount is the name of the storage account.
+	StorageAccount string `json:"storageAccount"`
+
+	// StorageAccountKey selects the access key of the storage account.
+	StorageAccountKey *corev1.SecretKeySelector `json:"storageAccountKey"`
+
+	// Container is the name of the blob container.
+	Container string `json:"container"`
+
+	// Endpoint overrides the storage endpoint, e.g. for sovereign clouds.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// FilesystemObjectStorage configures a local directory
+type FilesystemObjectStorage struct {
+	// Directory is the path blocks are stored under.
+	Directory string `json:"directory"`
+}
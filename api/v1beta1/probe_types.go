@@ -0,0 +1,46 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+// Probes overrides the timings of the probes of the thanos container.
+// Images of releases before v0.8.0, which do not serve the probe endpoints
+// on every component, get no probes.
+type Probes struct {
+	// Liveness tunes the liveness probe on /-/healthy.
+	Liveness *ProbeSettings `json:"liveness,omitempty"`
+
+	// Readiness tunes the readiness probe on /-/ready.
+	Readiness *ProbeSettings `json:"readiness,omitempty"`
+}
+
+// ProbeSettings tunes a probe, unset fields keep the operator defaults
+type ProbeSettings struct {
+	// Number of seconds after the container has started before the probe is initiated.
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// Number of seconds after which the probe times out.
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// How often in seconds to perform the probe.
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// Minimum consecutive successes for the probe to be considered successful after having failed.
+	SuccessThreshold *int32 `json:"successThreshold,omitempty"`
+
+	// Minimum consecutive failures for the probe to be considered failed after having succeeded.
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
@@ -34,6 +34,8 @@ type QuerierSpec struct {
 	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md
 	// Metadata Labels and Annotations gets propagated to the prometheus pods.
 	PodMetadata *metav1.ObjectMeta `json:"podMetadata,omitempty"`
+	// ServiceMetadata Labels and Annotations gets propagated to the generated Service.
+	ServiceMetadata *metav1.ObjectMeta `json:"serviceMetadata,omitempty"`
 	// ServiceMonitors to be selected for target discovery.
 	ServiceMonitorSelector *metav1.LabelSelector `json:"serviceMonitorSelector,omitempty"`
 	// Namespaces to be selected for ServiceMonitor discovery. If nil, only
@@ -43,6 +45,27 @@ type QuerierSpec struct {
 	// Define resources requests and limits for single Pods.
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// Probes overrides the timings of the liveness and readiness probes.
+	Probes *Probes `json:"probes,omitempty"`
+
+	// Define which Nodes the Pods are scheduled on.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// If specified, the pod's scheduling constraints. Topology spread
+	// constraints are not supported: the Kubernetes API the operator is built
+	// against (1.14) predates them, spread the pods with podAntiAffinity.
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// If specified, the pod's tolerations.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Priority class assigned to the Pods
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// SecurityContext holds pod-level security attributes and common container settings.
+	// This defaults to the default PodSecurityContext.
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+
 	// Number of instances to deploy for a Prometheus deployment.
 	Replicas *int32 `json:"replicas,omitempty"`
 
@@ -52,6 +75,28 @@ type QuerierSpec struct {
 	// storeDNS is storage gateway
 	StoreDNS string `json:"storeDNS,omitempty"`
 
+	// StoreSelector selects the Stores, Receivers and Sidecars in the
+	// querier's namespace whose StoreAPI is queried. If nil, none are.
+	StoreSelector *metav1.LabelSelector `json:"storeSelector,omitempty"`
+
+	// Stores are static StoreAPI endpoints (host:port) to query, e.g.
+	// Thanos components running outside of this cluster.
+	Stores []string `json:"stores,omitempty"`
+
+	// FileSD writes the static stores and the stores found by storeSelector
+	// into a generated file SD ConfigMap instead of passing them as flags,
+	// so that the list can change without restarting the querier pods.
+	FileSD bool `json:"fileSD,omitempty"`
+
+	// Version of Thanos to be deployed.
+	Version string `json:"version,omitempty"`
+	// Tag of Thanos container image to be deployed. Defaults to the value of `version`.
+	// Version is ignored if Tag is set.
+	Tag string `json:"tag,omitempty"`
+
+	// Base image to use for a Thanos deployment.
+	BaseImage string `json:"baseImage,omitempty"`
+
 	// Image if specified has precedence over baseImage, tag and sha
 	// combinations. Specifying the version is still necessary to ensure the
 	// Prometheus Operator knows what version of Prometheus is being
@@ -60,6 +105,29 @@ type QuerierSpec struct {
 
 	// Log level for Prometheus to be configured with.
 	LogLevel string `json:"logLevel,omitempty"`
+
+	// Secrets is a list of Secrets in the same namespace as the Querier
+	// object, which shall be mounted into the Querier Pods.
+	// The Secrets are mounted into /etc/thanos/user-secrets/<secret-name>.
+	Secrets []string `json:"secrets,omitempty"`
+
+	// Volumes allows configuration of additional volumes on the generated
+	// pods. Volumes specified will be appended to the generated ones.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+
+	// VolumeMounts allows configuration of additional VolumeMounts on the
+	// querier container. VolumeMounts specified will be appended to the
+	// generated ones.
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// InitContainers allows adding initContainers to the pod definition.
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+
+	// Containers allows injecting additional containers or modifying
+	// operator generated containers. Containers are merged with the
+	// generated ones by name using a strategic merge patch; the thanos
+	// container is named querier.
+	Containers []corev1.Container `json:"containers,omitempty"`
 }
 
 // QuerierStatus defines the observed state of Querier
@@ -71,6 +139,11 @@ type QuerierStatus struct {
 	// serviceStatus contains the status of the Service managed by thanos reciver
 	ServiceStatus corev1.ServiceStatus `json:"serviceStatus,omitempty"`
 
+	// Total number of non-terminated pods targeted by this deployment
+	// (their labels match the selector).
+	Replicas int32 `json:"replicas"`
+	// Selector is the label selector of the managed pods, used by the scale subresource.
+	Selector string `json:"selector,omitempty"`
 	// Total number of non-terminated pods targeted by this Prometheus deployment
 	// that have the desired version spec.
 	UpdatedReplicas int32 `json:"updatedReplicas"`
@@ -79,14 +152,20 @@ type QuerierStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas"`
 	// Total number of unavailable pods targeted by this Prometheus deployment.
 	UnavailableReplicas int32 `json:"unavailableReplicas"`
+
+	// ObservedGeneration is the most recent generation reconciled successfully.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the current state of the querier.
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:printcolumn:name="storage",type="string",JSONPath=".spec.storage",format="byte"
-// +kubebuilder:printcolumn:name="ready replicas",type="integer",JSONPath=".status.Deployment.readyReplicas",format="int32"
-// +kubebuilder:printcolumn:name="current replicas",type="integer",JSONPath=".status.Deployment.currentReplicas",format="int32"
+// +kubebuilder:printcolumn:name="ready replicas",type="integer",JSONPath=".status.deploymentStatus.readyReplicas",format="int32"
+// +kubebuilder:printcolumn:name="current replicas",type="integer",JSONPath=".status.deploymentStatus.currentReplicas",format="int32"
 
 // +kubebuilder:object:root=true
-// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.Deployment.replicas
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:subresource:status
 
 // Querier is the Schema for the queriers API
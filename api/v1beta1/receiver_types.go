@@ -34,6 +34,8 @@ type ReceiverSpec struct {
 	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md
 	// Metadata Labels and Annotations gets propagated to the prometheus pods.
 	PodMetadata *metav1.ObjectMeta `json:"podMetadata,omitempty"`
+	// ServiceMetadata Labels and Annotations gets propagated to the generated Service.
+	ServiceMetadata *metav1.ObjectMeta `json:"serviceMetadata,omitempty"`
 	// ServiceMonitors to be selected for target discovery.
 	ServiceMonitorSelector *metav1.LabelSelector `json:"serviceMonitorSelector,omitempty"`
 	// Namespaces to be selected for ServiceMonitor discovery. If nil, only
@@ -43,13 +45,29 @@ type ReceiverSpec struct {
 	// Number of instances to deploy for a Prometheus deployment.
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// ReplicationFactor is the number of times each series is replicated
+	// across the hashring. Only used when more than one replica is deployed.
+	// Can not exceed replicas, writes need a quorum of the replicas holding
+	// their series.
+	ReplicationFactor *int32 `json:"replicationFactor,omitempty"`
+
+	// TenantHeader is the HTTP header determining the tenant of a write request.
+	// Default is 'THANOS-TENANT'.
+	TenantHeader string `json:"tenantHeader,omitempty"`
+
+	// Tenants routes the write requests of each tenant to its own subset of
+	// receive replicas. Tenants not listed are spread over every replica.
+	Tenants []ReceiverTenant `json:"tenants,omitempty"`
+
 	// Version of Prometheus to be deployed.
 	Version string `json:"version,omitempty"`
 	// Tag of Prometheus container image to be deployed. Defaults to the value of `version`.
 	// Version is ignored if Tag is set.
 	Tag string `json:"tag,omitempty"`
 
-	// If specified, the pod's scheduling constraints.
+	// If specified, the pod's scheduling constraints. Topology spread
+	// constraints are not supported: the Kubernetes API the operator is built
+	// against (1.14) predates them, spread the pods with podAntiAffinity.
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
 	// Image if specified has precedence over baseImage, tag and sha
@@ -64,6 +82,9 @@ type ReceiverSpec struct {
 	// Define resources requests and limits for single Pods.
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// Probes overrides the timings of the liveness and readiness probes.
+	Probes *Probes `json:"probes,omitempty"`
+
 	// Time duration Prometheus shall retain data for. Default is '24h',
 	// and must match the regular expression `[0-9]+(ms|s|m|h|d|w|y)` (milliseconds seconds minutes hours days weeks years).
 	Retention string `json:"retention,omitempty"`
@@ -75,38 +96,99 @@ type ReceiverSpec struct {
 	LogLevel string `json:"logLevel,omitempty"`
 
 	// the receiver labels to set with receiver config
+	// Deprecated: use ExternalLabels instead, receiveLabels sets the
+	// receive external label unless ExternalLabels already does.
 	ReceiveLables string `json:"receiveLabels,omitempty"`
 
-	// object storage type GCS OR S3
+	// object storage type, only GCS is supported. Configure the other
+	// providers with objectStorage.
+	// Deprecated: use ObjectStorageConfig instead.
 	ObjectStorageType string `json:"objstoreType,omitempty"`
 
 	// secret name is gcs iam secret name
+	// Deprecated: use ObjectStorageConfig instead.
 	SecretName string `json:"secretName,omitempty"`
 
 	// object storage bucket name need set object storage type
+	// Deprecated: use ObjectStorageConfig instead.
 	BucketName string `json:"bucketName,omitempty"`
 
 	// The labels to add to any time series or alerts when communicating with
 	// external systems (federation, remote storage, Alertmanager).
+	// Label names must match the Prometheus label name rules.
 	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
 
 	// Storage spec to specify how storage shall be used.
+	// It may only grow: existing volumes are then expanded in place, which
+	// requires a StorageClass allowing volume expansion. Default is '2Gi'.
 	Storage string `json:"storage,omitempty"`
 
+	// StorageClassName is the StorageClass of the receiver volumes.
+	// Default is the default StorageClass of the cluster.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// VolumeClaimTemplate overrides the claim template of the receiver
+	// volumes. Its storage request and storage class take precedence over
+	// storage and storageClassName. Like those of a StatefulSet, it only
+	// applies to new volumes except for the storage request.
+	VolumeClaimTemplate *corev1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty"`
+
 	// Define which Nodes the Pods are scheduled on.
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 
-	// Secrets is a list of Secrets in the same namespace as the Prometheus
-	// object, which shall be mounted into the Prometheus Pods.
-	// The Secrets are mounted into /etc/prometheus/secrets/<secret-name>.
+	// If specified, the pod's tolerations.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Priority class assigned to the Pods
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// SecurityContext holds pod-level security attributes and common container settings.
+	// This defaults to the default PodSecurityContext.
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// Secrets is a list of Secrets in the same namespace as the Receiver
+	// object, which shall be mounted into the Receiver Pods.
+	// The Secrets are mounted into /etc/thanos/user-secrets/<secret-name>.
 	Secrets []string `json:"secrets,omitempty"`
 
-	// containers is entirely outside the scope of what the maintainers will support and by doing
-	// so, you accept that this behaviour may break at any time without notice.
+	// Volumes allows configuration of additional volumes on the generated
+	// pods. Volumes specified will be appended to the generated ones.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+
+	// VolumeMounts allows configuration of additional VolumeMounts on the
+	// receiver container. VolumeMounts specified will be appended to the
+	// generated ones.
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// InitContainers allows adding initContainers to the pod definition.
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+
+	// Containers allows injecting additional containers or modifying
+	// operator generated containers. Containers are merged with the
+	// generated ones by name using a strategic merge patch; the thanos
+	// container is named receiver.
 	Containers []corev1.Container `json:"containers,omitempty"`
 
 	// ObjectStorageConfig configures object storage in Thanos.
+	// The referenced secret key holds a complete thanos objstore configuration
+	// and takes precedence over objstoreType, secretName and bucketName.
 	ObjectStorageConfig *corev1.SecretKeySelector `json:"objectStorageConfig,omitempty"`
+
+	// ObjectStorage configures object storage in Thanos from typed fields.
+	// The operator renders it into a generated Secret. It is ignored when
+	// objectStorageConfig is set.
+	ObjectStorage *ObjectStorage `json:"objectStorage,omitempty"`
+}
+
+// ReceiverTenant maps a tenant to the receive replicas serving its writes
+type ReceiverTenant struct {
+	// Name is the tenant header value routed to this hashring, unique among
+	// the tenants of the receiver.
+	Name string `json:"name"`
+
+	// Replicas are the ordinals of the receive replicas serving this tenant,
+	// each below spec.replicas. At least spec.replicationFactor are needed.
+	Replicas []int32 `json:"replicas"`
 }
 
 // ReceiverStatus defines the observed state of Receiver
@@ -120,6 +202,11 @@ type ReceiverStatus struct {
 	// serviceStatus contains the status of the Service managed by thanos reciver
 	ServiceStatus corev1.ServiceStatus `json:"serviceStatus,omitempty"`
 
+	// Total number of non-terminated pods targeted by this deployment
+	// (their labels match the selector).
+	Replicas int32 `json:"replicas"`
+	// Selector is the label selector of the managed pods, used by the scale subresource.
+	Selector string `json:"selector,omitempty"`
 	// Total number of non-terminated pods targeted by this Prometheus deployment
 	// that have the desired version spec.
 	UpdatedReplicas int32 `json:"updatedReplicas"`
@@ -128,6 +215,12 @@ type ReceiverStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas"`
 	// Total number of unavailable pods targeted by this Prometheus deployment.
 	UnavailableReplicas int32 `json:"unavailableReplicas"`
+
+	// ObservedGeneration is the most recent generation reconciled successfully.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the current state of the receiver.
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:printcolumn:name="storage",type="string",JSONPath=".spec.storage",format="byte"
@@ -135,7 +228,7 @@ type ReceiverStatus struct {
 // +kubebuilder:printcolumn:name="current replicas",type="integer",JSONPath=".status.statefulSetStatus.currentReplicas",format="int32"
 
 // +kubebuilder:object:root=true
-// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.statefulSetStatus.replicas
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:subresource:status
 
 // Receiver is the Schema for the receivers API
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// SidecarSpec defines the desired state of Sidecar
+type SidecarSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Selector selects the Prometheus pods, in the same namespace as the
+	// Sidecar, the thanos sidecar container is injected into. Only pods
+	// labeled thanos.orangesys.io/inject=true are considered. Only
+	// matchLabels is used to select the pods behind the sidecar Service.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// ServiceMetadata Labels and Annotations gets propagated to the sidecar Service.
+	ServiceMetadata *metav1.ObjectMeta `json:"serviceMetadata,omitempty"`
+
+	// PrometheusURL is the URL the sidecar uses to reach Prometheus.
+	// Default is 'http://localhost:9090'.
+	PrometheusURL string `json:"prometheusURL,omitempty"`
+
+	// TSDBVolumeName is the name of the Prometheus pod volume holding its TSDB.
+	TSDBVolumeName string `json:"tsdbVolumeName,omitempty"`
+
+	// TSDBPath is the path the TSDB volume is mounted at. Default is '/prometheus'.
+	TSDBPath string `json:"tsdbPath,omitempty"`
+
+	// ReloaderConfigFile is the Prometheus config file watched by the sidecar.
+	// Together with ReloaderConfigEnvsubstFile it lets external labels in the
+	// Prometheus config reference $(POD_NAME) and $(POD_NAMESPACE), which the
+	// sidecar substitutes before reloading Prometheus.
+	ReloaderConfigFile string `json:"reloaderConfigFile,omitempty"`
+
+	// ReloaderConfigEnvsubstFile is the output file for the substituted Prometheus config.
+	ReloaderConfigEnvsubstFile string `json:"reloaderConfigEnvsubstFile,omitempty"`
+
+	// Define resources requests and limits for the sidecar container.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Probes overrides the timings of the liveness and readiness probes.
+	Probes *Probes `json:"probes,omitempty"`
+
+	// object storage type, only GCS is supported. Configure the other
+	// providers with objectStorage.
+	// Deprecated: use ObjectStorageConfig instead.
+	ObjectStorageType string `json:"objstoreType,omitempty"`
+
+	// secret name is gcs iam secret name
+	// Deprecated: use ObjectStorageConfig instead.
+	SecretName string `json:"secretName,omitempty"`
+
+	// object storage bucket name need set object storage type
+	// Deprecated: use ObjectStorageConfig instead.
+	BucketName string `json:"bucketName,omitempty"`
+
+	// ObjectStorageConfig configures object storage in Thanos.
+	// The referenced secret key holds a complete thanos objstore configuration
+	// and takes precedence over objstoreType, secretName and bucketName.
+	ObjectStorageConfig *corev1.SecretKeySelector `json:"objectStorageConfig,omitempty"`
+
+	// ObjectStorage configures object storage in Thanos from typed fields.
+	// The operator renders it into a generated Secret. It is ignored when
+	// objectStorageConfig is set.
+	ObjectStorage *ObjectStorage `json:"objectStorage,omitempty"`
+
+	// Image if specified has precedence over baseImage, tag and sha
+	// combinations. Specifying the version is still necessary to ensure the
+	// Thanos Operator knows what version of Thanos is being
+	// configured.
+	Image *string `json:"image,omitempty"`
+
+	// Log level for Thanos to be configured with.
+	LogLevel string `json:"logLevel,omitempty"`
+}
+
+// SidecarStatus defines the observed state of Sidecar
+type SidecarStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// serviceStatus contains the status of the Service managed by thanos sidecar
+	ServiceStatus corev1.ServiceStatus `json:"serviceStatus,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled successfully.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the current state of the sidecar.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// Sidecar is the Schema for the sidecars API
+type Sidecar struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SidecarSpec   `json:"spec,omitempty"`
+	Status SidecarStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SidecarList contains a list of Sidecar
+type SidecarList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Sidecar `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Sidecar{}, &SidecarList{})
+}
@@ -34,6 +34,8 @@ type StoreSpec struct {
 	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md
 	// Metadata Labels and Annotations gets propagated to the prometheus pods.
 	PodMetadata *metav1.ObjectMeta `json:"podMetadata,omitempty"`
+	// ServiceMetadata Labels and Annotations gets propagated to the generated Service.
+	ServiceMetadata *metav1.ObjectMeta `json:"serviceMetadata,omitempty"`
 	// ServiceMonitors to be selected for target discovery.
 	ServiceMonitorSelector *metav1.LabelSelector `json:"serviceMonitorSelector,omitempty"`
 	// Namespaces to be selected for ServiceMonitor discovery. If nil, only
@@ -43,39 +45,113 @@ type StoreSpec struct {
 	// Define resources requests and limits for single Pods.
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 
-	// object storage type GCS OR S3
+	// Probes overrides the timings of the liveness and readiness probes.
+	Probes *Probes `json:"probes,omitempty"`
+
+	// Number of instances to deploy for a store gateway. Each shard runs
+	// this many replicas.
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// object storage type, only GCS is supported. Configure the other
+	// providers with objectStorage.
+	// Deprecated: use ObjectStorageConfig instead.
 	ObjectStorageType string `json:"objstoreType,omitempty"`
 
 	// secret name is gcs iam secret name
+	// Deprecated: use ObjectStorageConfig instead.
 	SecretName string `json:"secretName,omitempty"`
 
 	// object storage bucket name need set object storage type
+	// Deprecated: use ObjectStorageConfig instead.
 	BucketName string `json:"bucketName,omitempty"`
 
 	// Define which Nodes the Pods are scheduled on.
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 
-	// Secrets is a list of Secrets in the same namespace as the Prometheus
-	// object, which shall be mounted into the Prometheus Pods.
-	// The Secrets are mounted into /etc/prometheus/secrets/<secret-name>.
+	// If specified, the pod's scheduling constraints. Topology spread
+	// constraints are not supported: the Kubernetes API the operator is built
+	// against (1.14) predates them, spread the pods with podAntiAffinity.
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// If specified, the pod's tolerations.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Priority class assigned to the Pods
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// SecurityContext holds pod-level security attributes and common container settings.
+	// This defaults to the default PodSecurityContext.
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// Secrets is a list of Secrets in the same namespace as the Store
+	// object, which shall be mounted into the Store Pods.
+	// The Secrets are mounted into /etc/thanos/user-secrets/<secret-name>.
 	Secrets []string `json:"secrets,omitempty"`
 
-	// containers is entirely outside the scope of what the maintainers will support and by doing
-	// so, you accept that this behaviour may break at any time without notice.
+	// Volumes allows configuration of additional volumes on the generated
+	// pods. Volumes specified will be appended to the generated ones.
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+
+	// VolumeMounts allows configuration of additional VolumeMounts on the
+	// store container. VolumeMounts specified will be appended to the
+	// generated ones.
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// InitContainers allows adding initContainers to the pod definition.
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+
+	// Containers allows injecting additional containers or modifying
+	// operator generated containers. Containers are merged with the
+	// generated ones by name using a strategic merge patch; the thanos
+	// container is named store.
 	Containers []corev1.Container `json:"containers,omitempty"`
 
 	// ObjectStorageConfig configures object storage in Thanos.
+	// The referenced secret key holds a complete thanos objstore configuration
+	// and takes precedence over objstoreType, secretName and bucketName.
 	ObjectStorageConfig *corev1.SecretKeySelector `json:"objectStorageConfig,omitempty"`
 
+	// ObjectStorage configures object storage in Thanos from typed fields.
+	// The operator renders it into a generated Secret. It is ignored when
+	// objectStorageConfig is set.
+	ObjectStorage *ObjectStorage `json:"objectStorage,omitempty"`
+
 	// DataDir is cache from objectstorage
 	DataDir string `json:"dataDir,omitempty"`
 
+	// Persistence keeps DataDir on a persistent volume when enabled.
+	Persistence *StorePersistence `json:"persistence,omitempty"`
+
 	// IndexCacheSize is index cache size with store
 	IndexCacheSize string `json:"indexCacheSize,omitempty"`
 
 	// ChunkPoolSize is chunk pool size with store
 	ChunkPoolSize string `json:"chunkPoolSize,omitempty"`
 
+	// MinTime is the start of the time range served by the store, as an
+	// RFC3339 time or a duration relative to now such as -2w.
+	// Default is the beginning of time. Requires Thanos v0.7.0 or later.
+	MinTime string `json:"minTime,omitempty"`
+
+	// MaxTime is the end of the time range served by the store, as an
+	// RFC3339 time or a duration relative to now such as -2w.
+	// Default is the end of time. Requires Thanos v0.7.0 or later.
+	MaxTime string `json:"maxTime,omitempty"`
+
+	// Shards splits the blocks of the bucket across several store
+	// workloads, each with its own Service. The Service named after the
+	// store selects all of them.
+	Shards *StoreShards `json:"shards,omitempty"`
+
+	// Version of Thanos to be deployed.
+	Version string `json:"version,omitempty"`
+	// Tag of Thanos container image to be deployed. Defaults to the value of `version`.
+	// Version is ignored if Tag is set.
+	Tag string `json:"tag,omitempty"`
+
+	// Base image to use for a Thanos deployment.
+	BaseImage string `json:"baseImage,omitempty"`
+
 	// Image if specified has precedence over baseImage, tag and sha
 	// combinations. Specifying the version is still necessary to ensure the
 	// Prometheus Operator knows what version of Prometheus is being
@@ -86,16 +162,69 @@ type StoreSpec struct {
 	LogLevel string `json:"logLevel,omitempty"`
 }
 
+// StorePersistence describes the persistent volumes of a store
+type StorePersistence struct {
+	// Enabled runs the store as a StatefulSet keeping DataDir on a persistent
+	// volume, so the index headers cached there survive restarts.
+	Enabled bool `json:"enabled"`
+
+	// Storage is the size of the persistent volume backing DataDir (e.g. 10Gi).
+	// Default is 10Gi. It may only grow: existing volumes are then expanded in
+	// place, which requires a StorageClass allowing volume expansion.
+	Storage string `json:"storage,omitempty"`
+
+	// StorageClassName is the StorageClass of the store volumes.
+	// Default is the default StorageClass of the cluster.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// StoreShards splits the blocks served by a store. Each time partition is
+// split into hashmodShards shards, every combination running as its own
+// Deployment, or StatefulSet when persistence is enabled.
+type StoreShards struct {
+	// HashmodShards is the number of shards the blocks are spread over by
+	// hashing their ID. Default is 1. More than one shard requires Thanos
+	// v0.12.0 or later.
+	HashmodShards *int32 `json:"hashmodShards,omitempty"`
+
+	// TimePartitions splits the time range served into partitions, which
+	// must not overlap. Default is the range of minTime and maxTime.
+	// Requires Thanos v0.7.0 or later.
+	TimePartitions []StoreTimePartition `json:"timePartitions,omitempty"`
+}
+
+// StoreTimePartition is a time range served by a store shard. Times are
+// RFC3339 times or durations relative to now such as -2w.
+type StoreTimePartition struct {
+	// MinTime is the start of the partition. Default is the beginning of time.
+	MinTime string `json:"minTime,omitempty"`
+
+	// MaxTime is the end of the partition. Default is the end of time.
+	MaxTime string `json:"maxTime,omitempty"`
+}
+
 // StoreStatus defines the observed state of Store
 type StoreStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	// deploymentStatus contains the status of the deployment managed by Thanos
+	// It is left empty when the store is sharded or runs as a StatefulSet.
 	DeploymentStatus appsv1.DeploymentStatus `json:"deploymentStatus,omitempty"`
 
+	// statefulSetStatus contains the status of the StatefulSet managed by Thanos
+	// It is left empty when the store is sharded or runs as a Deployment.
+	StatefulSetStatus appsv1.StatefulSetStatus `json:"statefulSetStatus,omitempty"`
+
 	// serviceStatus contains the status of the Service managed by thanos reciver
 	ServiceStatus corev1.ServiceStatus `json:"serviceStatus,omitempty"`
 
+	// Total number of non-terminated pods of the first shard, the one
+	// matched by selector. Like spec.replicas it counts the pods of a
+	// single shard.
+	Replicas int32 `json:"replicas"`
+	// Selector is the label selector of the pods of the first shard, used by
+	// the scale subresource.
+	Selector string `json:"selector,omitempty"`
 	// Total number of non-terminated pods targeted by this Prometheus deployment
 	// that have the desired version spec.
 	UpdatedReplicas int32 `json:"updatedReplicas"`
@@ -104,14 +233,24 @@ type StoreStatus struct {
 	AvailableReplicas int32 `json:"availableReplicas"`
 	// Total number of unavailable pods targeted by this Prometheus deployment.
 	UnavailableReplicas int32 `json:"unavailableReplicas"`
-}
 
-// +kubebuilder:printcolumn:name="storage",type="string",JSONPath=".spec.storage",format="byte"
-// +kubebuilder:printcolumn:name="ready replicas",type="integer",JSONPath=".status.Deployment.readyReplicas",format="int32"
-// +kubebuilder:printcolumn:name="current replicas",type="integer",JSONPath=".status.Deployment.currentReplicas",format="int32"
+	// Shards is the number of store Deployments or StatefulSets, the
+	// updated, available and unavailable replica counts above summing theirs.
+	Shards int32 `json:"shards,omitempty"`
+
+	// ObservedGeneration is the most recent generation reconciled successfully.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the current state of the store.
+	Conditions []Condition `json:"conditions,omitempty"`
+}
 
+// +kubebuilder:printcolumn:name="storage",type="string",JSONPath=".spec.persistence.storage"
+// +kubebuilder:printcolumn:name="available replicas",type="integer",JSONPath=".status.availableReplicas",format="int32"
+// +kubebuilder:printcolumn:name="updated replicas",type="integer",JSONPath=".status.updatedReplicas",format="int32"
+// +kubebuilder:printcolumn:name="shards",type="integer",JSONPath=".status.shards",format="int32"
 // +kubebuilder:object:root=true
-// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.Deployment.replicas
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:subresource:status
 
 // Store is the Schema for the stores API
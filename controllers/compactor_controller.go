@@ -0,0 +1,204 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// CompactorReconciler reconciles a Compactor object
+type CompactorReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+	// DefaultResources are the requests given to containers which leave them out
+	DefaultResources corev1.ResourceList
+}
+
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=compactors,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=compactors/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+
+func (r *CompactorReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("compactor", req.NamespacedName)
+
+	// Fetch the compactor instance
+	compactor := &thanosv1beta1.Compactor{}
+	if err := r.Get(ctx, req.NamespacedName, compactor); err != nil {
+		if ignoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch thanos compactor")
+		return ctrl.Result{}, err
+	}
+
+	// Record failed reconciliations in the status
+	defer func() {
+		if err == nil {
+			return
+		}
+		compactor.Status.Conditions = setReconcileErrorCondition(compactor.Status.Conditions, compactor.Generation, err)
+		if err := r.Status().Update(ctx, compactor); err != nil {
+			log.Error(err, "unable to record reconcile error")
+		}
+	}()
+
+	// Generate object storage Secret
+	if err := reconcileObjstoreSecret(ctx, r.Client, r.Scheme, compactor, compactor.Spec.ObjectStorageConfig, compactor.Spec.ObjectStorage); err != nil {
+		log.Error(err, "unable to generate object storage Secret")
+		return ctrl.Result{}, err
+	}
+
+	// Generate Service
+	service := &corev1.Service{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+	}
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
+		makeService(service, componentCompactor, compactor.Name, compactor.Spec.ServiceMetadata)
+		return controllerutil.SetControllerReference(compactor, service, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Hash the inputs of the pods, rolling them when one changes
+	inputHash, err := hashInputs(ctx, r.Client, req.Namespace, compactorSecrets(*compactor))
+	if err != nil {
+		log.Error(err, "unable to hash pod inputs")
+		return ctrl.Result{}, err
+	}
+
+	// Generate StatefulSet
+	ss := &appsv1.StatefulSet{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+	}
+
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, ss, func() error {
+		if err := setCompactorStatefulSet(
+			ss,
+			service,
+			r.DefaultResources,
+			*compactor,
+		); err != nil {
+			return err
+		}
+		setInputHash(&ss.Spec.Template, inputHash)
+		return controllerutil.SetControllerReference(compactor, ss, r.Scheme)
+	})
+
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update Status
+	ssNN := req.NamespacedName
+	ssNN.Name = ss.Name
+	if err := r.Get(ctx, ssNN, ss); err != nil {
+		log.Error(err, "unable to fetch StatusfulSet", "namespaceName", ssNN)
+		return ctrl.Result{}, err
+	}
+	compactor.Status.StatefulSetStatus = ss.Status
+
+	serviceNN := req.NamespacedName
+	serviceNN.Name = service.Name
+	if err := r.Get(ctx, serviceNN, service); err != nil {
+		log.Error(err, "unable to fetch Service", "namespaceName", serviceNN)
+		return ctrl.Result{}, err
+	}
+	compactor.Status.ServiceStatus = service.Status
+
+	compactor.Status.ObservedGeneration = compactor.Generation
+	compactor.Status.Conditions = setWorkloadConditions(
+		compactor.Status.Conditions,
+		compactor.Generation,
+		ss.Status.ObservedGeneration >= ss.Generation,
+		*ss.Spec.Replicas,
+		ss.Status.UpdatedReplicas,
+		ss.Status.ReadyReplicas,
+	)
+	compactor.Status.Conditions = setReconcileErrorCondition(compactor.Status.Conditions, compactor.Generation, nil)
+
+	err = r.Status().Update(ctx, compactor)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// compactorsForSecret maps a Secret event to every Compactor in the same namespace
+// whose pods read it.
+func (r *CompactorReconciler) compactorsForSecret(obj handler.MapObject) []reconcile.Request {
+	compactors := &thanosv1beta1.CompactorList{}
+	if err := r.List(context.Background(), compactors, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list compactors", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, compactor := range compactors.Items {
+		if containsString(compactorSecrets(compactor), obj.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      compactor.Name,
+					Namespace: compactor.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *CompactorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&thanosv1beta1.Compactor{}).
+		Owns(&appsv1.StatefulSet{}). // Generates StatefulSets
+		Owns(&corev1.Service{}).     // Generates Services
+		Owns(&corev1.Secret{}).      // Generates object storage Secrets
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.compactorsForSecret),
+		}).
+		Complete(r)
+}
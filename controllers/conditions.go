@@ -0,0 +1,136 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// setCondition adds condition to conditions, replacing the one of the same
+// type. The transition time only moves when the status changes.
+func setCondition(conditions []thanosv1beta1.Condition, condition thanosv1beta1.Condition) []thanosv1beta1.Condition {
+	condition.LastTransitionTime = metav1.Now()
+	for i, c := range conditions {
+		if c.Type != condition.Type {
+			continue
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
+
+// setWorkloadConditions sets the Available, Progressing and Degraded
+// conditions from the replica counts of the workload backing a component.
+// observed tells whether the workload controller has seen the latest spec.
+func setWorkloadConditions(
+	conditions []thanosv1beta1.Condition,
+	generation int64,
+	observed bool,
+	desired, updated, available int32,
+) []thanosv1beta1.Condition {
+	progressing := !observed || updated < desired
+	unavailable := available < desired
+
+	availableCondition := thanosv1beta1.Condition{
+		Type:               thanosv1beta1.ConditionAvailable,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "AllReplicasAvailable",
+	}
+	if unavailable {
+		availableCondition.Status = corev1.ConditionFalse
+		availableCondition.Reason = "ReplicasUnavailable"
+	}
+
+	progressingCondition := thanosv1beta1.Condition{
+		Type:               thanosv1beta1.ConditionProgressing,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "RolloutComplete",
+	}
+	if progressing {
+		progressingCondition.Status = corev1.ConditionTrue
+		progressingCondition.Reason = "RollingUpdate"
+	}
+
+	degradedCondition := thanosv1beta1.Condition{
+		Type:               thanosv1beta1.ConditionDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+	}
+	if unavailable && !progressing {
+		degradedCondition.Status = corev1.ConditionTrue
+		degradedCondition.Reason = "ReplicasUnavailable"
+	}
+
+	conditions = setCondition(conditions, availableCondition)
+	conditions = setCondition(conditions, progressingCondition)
+	return setCondition(conditions, degradedCondition)
+}
+
+// setReconcileErrorCondition records the outcome of a reconciliation, err
+// being nil when it succeeded.
+func setReconcileErrorCondition(
+	conditions []thanosv1beta1.Condition,
+	generation int64,
+	err error,
+) []thanosv1beta1.Condition {
+	condition := thanosv1beta1.Condition{
+		Type:               thanosv1beta1.ConditionReconcileError,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "ReconcileSucceeded",
+	}
+	if err != nil {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "ReconcileFailed"
+		condition.Message = err.Error()
+	}
+	return setCondition(conditions, condition)
+}
+
+// setStorageResizingCondition reports the expansion of the volumes of a
+// component to storage, pending of their total count being still smaller.
+func setStorageResizingCondition(
+	conditions []thanosv1beta1.Condition,
+	generation int64,
+	pending, total int,
+	storage string,
+) []thanosv1beta1.Condition {
+	condition := thanosv1beta1.Condition{
+		Type:               thanosv1beta1.ConditionStorageResizing,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "VolumesResized",
+	}
+	if pending > 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "VolumesResizing"
+		condition.Message = fmt.Sprintf("%d of %d volumes resized to %s", total-pending, total, storage)
+	}
+	return setCondition(conditions, condition)
+}
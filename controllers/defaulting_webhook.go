@@ -0,0 +1,198 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// QuerierDefaulter fills the unset fields of Queriers with their defaults
+type QuerierDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/mutate-thanos-orangesys-io-v1beta1-querier,mutating=true,failurePolicy=fail,groups=thanos.orangesys.io,resources=queriers,verbs=create;update,versions=v1beta1,name=mquerier.thanos.orangesys.io
+
+// Handle patches the defaults into the Querier of req
+func (d *QuerierDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	querier, old := &thanosv1beta1.Querier{}, &thanosv1beta1.Querier{}
+	if err := decodeRequest(d.decoder, req, querier, old); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == admissionv1beta1.Update {
+		rederiveImage(&querier.Spec.Image, old.Spec.Image, old.Spec.BaseImage, old.Spec.Version, old.Spec.Tag)
+	}
+	defaultQuerier(querier)
+	return defaultingResponse(req, querier)
+}
+
+// InjectDecoder injects the decoder into a QuerierDefaulter.
+func (d *QuerierDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// StoreDefaulter fills the unset fields of Stores with their defaults
+type StoreDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/mutate-thanos-orangesys-io-v1beta1-store,mutating=true,failurePolicy=fail,groups=thanos.orangesys.io,resources=stores,verbs=create;update,versions=v1beta1,name=mstore.thanos.orangesys.io
+
+// Handle patches the defaults into the Store of req
+func (d *StoreDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	store, old := &thanosv1beta1.Store{}, &thanosv1beta1.Store{}
+	if err := decodeRequest(d.decoder, req, store, old); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == admissionv1beta1.Update {
+		rederiveImage(&store.Spec.Image, old.Spec.Image, old.Spec.BaseImage, old.Spec.Version, old.Spec.Tag)
+	}
+	defaultStore(store)
+	return defaultingResponse(req, store)
+}
+
+// InjectDecoder injects the decoder into a StoreDefaulter.
+func (d *StoreDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// ReceiverDefaulter fills the unset fields of Receivers with their defaults
+type ReceiverDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/mutate-thanos-orangesys-io-v1beta1-receiver,mutating=true,failurePolicy=fail,groups=thanos.orangesys.io,resources=receivers,verbs=create;update,versions=v1beta1,name=mreceiver.thanos.orangesys.io
+
+// Handle patches the defaults into the Receiver of req
+func (d *ReceiverDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	receiver, old := &thanosv1beta1.Receiver{}, &thanosv1beta1.Receiver{}
+	if err := decodeRequest(d.decoder, req, receiver, old); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if req.Operation == admissionv1beta1.Update {
+		rederiveImage(&receiver.Spec.Image, old.Spec.Image, old.Spec.BaseImage, old.Spec.Version, old.Spec.Tag)
+	}
+	defaultReceiver(receiver)
+	return defaultingResponse(req, receiver)
+}
+
+// InjectDecoder injects the decoder into a ReceiverDefaulter.
+func (d *ReceiverDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// CompactorDefaulter fills the unset fields of Compactors with their defaults
+type CompactorDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/mutate-thanos-orangesys-io-v1beta1-compactor,mutating=true,failurePolicy=fail,groups=thanos.orangesys.io,resources=compactors,verbs=create;update,versions=v1beta1,name=mcompactor.thanos.orangesys.io
+
+// Handle patches the defaults into the Compactor of req
+func (d *CompactorDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	compactor := &thanosv1beta1.Compactor{}
+	if err := d.decoder.Decode(req, compactor); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	defaultCompactor(compactor)
+	return defaultingResponse(req, compactor)
+}
+
+// InjectDecoder injects the decoder into a CompactorDefaulter.
+func (d *CompactorDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// RulerDefaulter fills the unset fields of Rulers with their defaults
+type RulerDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/mutate-thanos-orangesys-io-v1beta1-ruler,mutating=true,failurePolicy=fail,groups=thanos.orangesys.io,resources=rulers,verbs=create;update,versions=v1beta1,name=mruler.thanos.orangesys.io
+
+// Handle patches the defaults into the Ruler of req
+func (d *RulerDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ruler := &thanosv1beta1.Ruler{}
+	if err := d.decoder.Decode(req, ruler); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	defaultRuler(ruler)
+	return defaultingResponse(req, ruler)
+}
+
+// InjectDecoder injects the decoder into a RulerDefaulter.
+func (d *RulerDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// rederiveImage clears *image when the update keeps the old image and that
+// image was derived from the old baseImage, version and tag, so that it gets
+// derived again from the new ones
+func rederiveImage(image **string, old *string, baseImage, version, tag string) {
+	if *image == nil || old == nil || **image != *old {
+		return
+	}
+	if *old == *thanosImage(baseImage, version, tag) {
+		*image = nil
+	}
+}
+
+// SidecarDefaulter fills the unset fields of Sidecars with their defaults
+type SidecarDefaulter struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/mutate-thanos-orangesys-io-v1beta1-sidecar,mutating=true,failurePolicy=fail,groups=thanos.orangesys.io,resources=sidecars,verbs=create;update,versions=v1beta1,name=msidecar.thanos.orangesys.io
+
+// Handle patches the defaults into the Sidecar of req
+func (d *SidecarDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	sidecar := &thanosv1beta1.Sidecar{}
+	if err := d.decoder.Decode(req, sidecar); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	defaultSidecar(sidecar)
+	return defaultingResponse(req, sidecar)
+}
+
+// InjectDecoder injects the decoder into a SidecarDefaulter.
+func (d *SidecarDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// rederiveImage clears the object of req into obj
+func defaultingResponse(req admission.Request, obj runtime.Object) admission.Response {
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
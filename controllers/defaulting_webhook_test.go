@@ -0,0 +1,147 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// patchedPaths returns the values set by the patches of resp, by path
+func patchedPaths(resp admission.Response) map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, patch := range resp.Patches {
+		paths[patch.Path] = patch.Value
+	}
+	return paths
+}
+
+var _ = Describe("Defaulting webhooks", func() {
+	It("should default the image of queriers", func() {
+		defaulter := &QuerierDefaulter{}
+		Expect(defaulter.InjectDecoder(newTestDecoder())).To(Succeed())
+		querier := &thanosv1beta1.Querier{TypeMeta: typeMeta("Querier")}
+
+		resp := defaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, querier, nil))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(patchedPaths(resp)).To(HaveKeyWithValue("/spec/image", *thanosImage("", "", "")))
+	})
+
+	It("should derive the image again when the version changes", func() {
+		defaulter := &StoreDefaulter{}
+		Expect(defaulter.InjectDecoder(newTestDecoder())).To(Succeed())
+		old := &thanosv1beta1.Store{
+			TypeMeta: typeMeta("Store"),
+			Spec: thanosv1beta1.StoreSpec{
+				Version: "v0.11.0",
+				Image:   thanosImage("", "v0.11.0", ""),
+			},
+		}
+		store := old.DeepCopy()
+		store.Spec.Version = "v0.12.2"
+
+		resp := defaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Update, store, old))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(patchedPaths(resp)).To(HaveKeyWithValue("/spec/image", *thanosImage("", "v0.12.2", "")))
+
+		// an image set by the user is kept
+		image := "example.com/thanos:custom"
+		old.Spec.Image = &image
+		store = old.DeepCopy()
+		store.Spec.Version = "v0.12.2"
+
+		resp = defaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Update, store, old))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(patchedPaths(resp)).NotTo(HaveKey("/spec/image"))
+	})
+
+	It("should default the caches of stores", func() {
+		defaulter := &StoreDefaulter{}
+		Expect(defaulter.InjectDecoder(newTestDecoder())).To(Succeed())
+		store := &thanosv1beta1.Store{
+			TypeMeta: typeMeta("Store"),
+			Spec:     thanosv1beta1.StoreSpec{IndexCacheSize: "1GB"},
+		}
+
+		resp := defaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, store, nil))
+
+		Expect(resp.Allowed).To(BeTrue())
+		paths := patchedPaths(resp)
+		Expect(paths).To(HaveKeyWithValue("/spec/chunkPoolSize", storeChunkPoolSize))
+		Expect(paths).NotTo(HaveKey("/spec/indexCacheSize"))
+	})
+
+	It("should default the storage of receivers", func() {
+		defaulter := &ReceiverDefaulter{}
+		Expect(defaulter.InjectDecoder(newTestDecoder())).To(Succeed())
+		receiver := &thanosv1beta1.Receiver{TypeMeta: typeMeta("Receiver")}
+
+		resp := defaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, receiver, nil))
+
+		Expect(resp.Allowed).To(BeTrue())
+		paths := patchedPaths(resp)
+		Expect(paths).To(HaveKeyWithValue("/spec/storage", receiveStorage))
+		Expect(paths).To(HaveKeyWithValue("/spec/retention", defaultRetetion))
+	})
+
+	It("should default compactors and rulers", func() {
+		compactorDefaulter := &CompactorDefaulter{}
+		Expect(compactorDefaulter.InjectDecoder(newTestDecoder())).To(Succeed())
+		compactor := &thanosv1beta1.Compactor{TypeMeta: typeMeta("Compactor")}
+
+		resp := compactorDefaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, compactor, nil))
+
+		Expect(resp.Allowed).To(BeTrue())
+		paths := patchedPaths(resp)
+		Expect(paths).To(HaveKeyWithValue("/spec/image", *thanosImage("", "", "")))
+		Expect(paths).To(HaveKeyWithValue("/spec/storage", compactorStorage))
+
+		rulerDefaulter := &RulerDefaulter{}
+		Expect(rulerDefaulter.InjectDecoder(newTestDecoder())).To(Succeed())
+		ruler := &thanosv1beta1.Ruler{TypeMeta: typeMeta("Ruler")}
+
+		resp = rulerDefaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, ruler, nil))
+
+		Expect(resp.Allowed).To(BeTrue())
+		paths = patchedPaths(resp)
+		Expect(paths).To(HaveKeyWithValue("/spec/dataDir", rulerDir))
+		Expect(paths).To(HaveKeyWithValue("/spec/retention", defaultRetetion))
+	})
+
+	It("should default the image and TSDB path of sidecars", func() {
+		defaulter := &SidecarDefaulter{}
+		Expect(defaulter.InjectDecoder(newTestDecoder())).To(Succeed())
+		sidecar := &thanosv1beta1.Sidecar{TypeMeta: typeMeta("Sidecar")}
+
+		resp := defaulter.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, sidecar, nil))
+
+		Expect(resp.Allowed).To(BeTrue())
+		paths := patchedPaths(resp)
+		Expect(paths).To(HaveKeyWithValue("/spec/image", *thanosImage("", "", "")))
+		Expect(paths).To(HaveKeyWithValue("/spec/tsdbPath", prometheusTSDBPath))
+	})
+})
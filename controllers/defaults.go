@@ -0,0 +1,134 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+const (
+	defaultThanosBaseImage = "quay.io/thanos/thanos"
+	storeDir               = "/thanos-store"
+	storeIndexCacheSize    = "250MB"
+	storeChunkPoolSize     = "2GB"
+	storeStorage           = "10Gi"
+)
+
+// thanosImage returns the image built from baseImage and tag, falling back to
+// version and then to defaultThanosVersion when no tag is given
+func thanosImage(baseImage, version, tag string) *string {
+	if baseImage == "" {
+		baseImage = defaultThanosBaseImage
+	}
+	if tag == "" {
+		tag = version
+	}
+	if tag == "" {
+		tag = defaultThanosVersion
+	}
+	image := baseImage + ":" + tag
+	return &image
+}
+
+// defaultQuerier fills the unset fields of t with their defaults
+func defaultQuerier(t *thanosv1beta1.Querier) {
+	if t.Spec.Image == nil || *t.Spec.Image == "" {
+		t.Spec.Image = thanosImage(t.Spec.BaseImage, t.Spec.Version, t.Spec.Tag)
+	}
+}
+
+// defaultStore fills the unset fields of t with their defaults
+func defaultStore(t *thanosv1beta1.Store) {
+	if t.Spec.Image == nil || *t.Spec.Image == "" {
+		t.Spec.Image = thanosImage(t.Spec.BaseImage, t.Spec.Version, t.Spec.Tag)
+	}
+	if t.Spec.DataDir == "" {
+		t.Spec.DataDir = storeDir
+	}
+	if t.Spec.IndexCacheSize == "" {
+		t.Spec.IndexCacheSize = storeIndexCacheSize
+	}
+	if t.Spec.ChunkPoolSize == "" {
+		t.Spec.ChunkPoolSize = storeChunkPoolSize
+	}
+	if storePersistent(*t) && t.Spec.Persistence.Storage == "" {
+		t.Spec.Persistence.Storage = storeStorage
+	}
+}
+
+// storePersistent reports whether t keeps its data dir on persistent volumes
+func storePersistent(t thanosv1beta1.Store) bool {
+	return t.Spec.Persistence != nil && t.Spec.Persistence.Enabled
+}
+
+// defaultReceiver fills the unset fields of t with their defaults
+func defaultReceiver(t *thanosv1beta1.Receiver) {
+	if t.Spec.Image == nil || *t.Spec.Image == "" {
+		t.Spec.Image = thanosImage(t.Spec.BaseImage, t.Spec.Version, t.Spec.Tag)
+	}
+	if t.Spec.Retention == "" {
+		t.Spec.Retention = defaultRetetion
+	}
+	if t.Spec.ReceivePrefix == "" {
+		t.Spec.ReceivePrefix = receiverDir
+	}
+	if t.Spec.Storage == "" {
+		t.Spec.Storage = receiveStorage
+	}
+}
+
+// defaultCompactor fills the unset fields of t with their defaults
+func defaultCompactor(t *thanosv1beta1.Compactor) {
+	if t.Spec.Image == nil || *t.Spec.Image == "" {
+		t.Spec.Image = thanosImage("", "", "")
+	}
+	if t.Spec.Storage == "" {
+		t.Spec.Storage = compactorStorage
+	}
+	if t.Spec.DataDir == "" {
+		t.Spec.DataDir = compactorDir
+	}
+}
+
+// defaultSidecar fills the unset fields of t with their defaults
+func defaultSidecar(t *thanosv1beta1.Sidecar) {
+	if t.Spec.Image == nil || *t.Spec.Image == "" {
+		t.Spec.Image = thanosImage("", "", "")
+	}
+	if t.Spec.PrometheusURL == "" {
+		t.Spec.PrometheusURL = prometheusURL
+	}
+	if t.Spec.TSDBPath == "" {
+		t.Spec.TSDBPath = prometheusTSDBPath
+	}
+}
+
+// defaultRuler fills the unset fields of t with their defaults
+func defaultRuler(t *thanosv1beta1.Ruler) {
+	if t.Spec.Image == nil || *t.Spec.Image == "" {
+		t.Spec.Image = thanosImage("", "", "")
+	}
+	if t.Spec.Storage == "" {
+		t.Spec.Storage = rulerStorage
+	}
+	if t.Spec.DataDir == "" {
+		t.Spec.DataDir = rulerDir
+	}
+	if t.Spec.Retention == "" {
+		t.Spec.Retention = defaultRetetion
+	}
+}
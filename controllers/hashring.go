@@ -0,0 +1,132 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+const (
+	hashringsFile       = "hashrings.json"
+	hashringsDir        = "/etc/thanos/hashrings/"
+	defaultHashring     = "default"
+	defaultTenantHeader = "THANOS-TENANT"
+	receiveRemotePath   = "/api/v1/receive"
+)
+
+// hashring mirrors the hashring configuration read by thanos receive
+type hashring struct {
+	Hashring  string   `json:"hashring,omitempty"`
+	Tenants   []string `json:"tenants,omitempty"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// receiverReplicas returns the number of receive replicas requested by t
+func receiverReplicas(t thanosv1beta1.Receiver) int32 {
+	if t.Spec.Replicas == nil {
+		return miniReplicas
+	}
+	return *t.Spec.Replicas
+}
+
+// receiverReplicationFactor returns the number of times each series is
+// replicated across the hashring of t
+func receiverReplicationFactor(t thanosv1beta1.Receiver) int32 {
+	if t.Spec.ReplicationFactor == nil {
+		return 1
+	}
+	return *t.Spec.ReplicationFactor
+}
+
+// hashringConfigMapName returns the name of the generated hashring ConfigMap
+func hashringConfigMapName(t thanosv1beta1.Receiver) string {
+	return t.Name + "-hashrings"
+}
+
+// receiveEndpoint returns the remote-write endpoint of a receive pod, addressed
+// through the stable DNS name provided by the governing service.
+func receiveEndpoint(pod, serviceName, namespace string) string {
+	return fmt.Sprintf("http://%s.%s.%s.svc.cluster.local:19291%s", pod, serviceName, namespace, receiveRemotePath)
+}
+
+// receiverNeedsHashring reports whether t has to be given a hashring file
+func receiverNeedsHashring(t thanosv1beta1.Receiver) bool {
+	return receiverReplicas(t) > 1 || len(t.Spec.Tenants) > 0
+}
+
+// makeHashrings builds one hashring per tenant of t, followed by the default
+// hashring spanning every receive replica. thanos receive picks the first
+// hashring matching a tenant, so the default one must come last. Tenants
+// routed to replicas which do not exist are an error rather than left to
+// the default hashring.
+func makeHashrings(t thanosv1beta1.Receiver, serviceName string) ([]hashring, error) {
+	if errs := validateTenants(t, field.NewPath("spec", "tenants")); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+
+	replicas := receiverReplicas(t)
+	endpoint := func(ordinal int32) string {
+		return receiveEndpoint(fmt.Sprintf("%s-%d", t.Name, ordinal), serviceName, t.Namespace)
+	}
+
+	var hashrings []hashring
+	for _, tenant := range t.Spec.Tenants {
+		var endpoints []string
+		for _, ordinal := range tenant.Replicas {
+			endpoints = append(endpoints, endpoint(ordinal))
+		}
+		hashrings = append(hashrings, hashring{
+			Hashring:  tenant.Name,
+			Tenants:   []string{tenant.Name},
+			Endpoints: endpoints,
+		})
+	}
+
+	var endpoints []string
+	for i := int32(0); i < replicas; i++ {
+		endpoints = append(endpoints, endpoint(i))
+	}
+	return append(hashrings, hashring{
+		Hashring:  defaultHashring,
+		Endpoints: endpoints,
+	}), nil
+}
+
+// makeHashringConfigMap renders the hashrings of t into cm
+func makeHashringConfigMap(cm *corev1.ConfigMap, t thanosv1beta1.Receiver, serviceName string) error {
+	hashrings, err := makeHashrings(t, serviceName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(hashrings, "", "  ")
+	if err != nil {
+		return err
+	}
+	cm.Labels = map[string]string{
+		"thanos": t.Name,
+	}
+	cm.Data = map[string]string{
+		hashringsFile: string(data),
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+var _ = Describe("Hashrings", func() {
+	var receiver thanosv1beta1.Receiver
+
+	BeforeEach(func() {
+		replicas := int32(3)
+		receiver = thanosv1beta1.Receiver{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ingest",
+				Namespace: "monitoring",
+			},
+			Spec: thanosv1beta1.ReceiverSpec{
+				Replicas: &replicas,
+			},
+		}
+	})
+
+	It("should spread the default hashring over every replica", func() {
+		hashrings, err := makeHashrings(receiver, "ingest-headless")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(hashrings).To(Equal([]hashring{{
+			Hashring: defaultHashring,
+			Endpoints: []string{
+				"http://ingest-0.ingest-headless.monitoring.svc.cluster.local:19291/api/v1/receive",
+				"http://ingest-1.ingest-headless.monitoring.svc.cluster.local:19291/api/v1/receive",
+				"http://ingest-2.ingest-headless.monitoring.svc.cluster.local:19291/api/v1/receive",
+			},
+		}}))
+	})
+
+	It("should route tenants to their replicas ahead of the default hashring", func() {
+		receiver.Spec.Tenants = []thanosv1beta1.ReceiverTenant{
+			{Name: "team-a", Replicas: []int32{0, 2}},
+			{Name: "team-b", Replicas: []int32{1}},
+		}
+
+		hashrings, err := makeHashrings(receiver, "ingest-headless")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(hashrings).To(HaveLen(3))
+		Expect(hashrings[0]).To(Equal(hashring{
+			Hashring: "team-a",
+			Tenants:  []string{"team-a"},
+			Endpoints: []string{
+				"http://ingest-0.ingest-headless.monitoring.svc.cluster.local:19291/api/v1/receive",
+				"http://ingest-2.ingest-headless.monitoring.svc.cluster.local:19291/api/v1/receive",
+			},
+		}))
+		Expect(hashrings[1].Hashring).To(Equal("team-b"))
+		Expect(hashrings[2].Hashring).To(Equal(defaultHashring))
+		Expect(hashrings[2].Endpoints).To(HaveLen(3))
+	})
+
+	It("should reject tenants routed to replicas which do not exist", func() {
+		for _, ordinal := range []int32{-1, 3} {
+			receiver.Spec.Tenants = []thanosv1beta1.ReceiverTenant{
+				{Name: "team-a", Replicas: []int32{0, ordinal}},
+			}
+
+			_, err := makeHashrings(receiver, "ingest-headless")
+			Expect(err).To(HaveOccurred(), "ordinal %d", ordinal)
+
+			errs := validateTenants(receiver, field.NewPath("spec", "tenants"))
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("spec.tenants[0].replicas[1]"))
+		}
+	})
+
+	It("should reject tenants without replicas", func() {
+		receiver.Spec.Tenants = []thanosv1beta1.ReceiverTenant{
+			{Name: "team-a"},
+		}
+
+		_, err := makeHashrings(receiver, "ingest-headless")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject duplicate tenants", func() {
+		receiver.Spec.Tenants = []thanosv1beta1.ReceiverTenant{
+			{Name: "team-a", Replicas: []int32{0}},
+			{Name: "team-a", Replicas: []int32{1}},
+		}
+
+		_, err := makeHashrings(receiver, "ingest-headless")
+		Expect(err).To(HaveOccurred())
+
+		errs := validateTenants(receiver, field.NewPath("spec", "tenants"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("spec.tenants[1].name"))
+	})
+
+	It("should reject tenants with fewer replicas than the replication factor", func() {
+		replicationFactor := int32(2)
+		receiver.Spec.ReplicationFactor = &replicationFactor
+		receiver.Spec.Tenants = []thanosv1beta1.ReceiverTenant{
+			{Name: "team-a", Replicas: []int32{0, 2}},
+			{Name: "team-b", Replicas: []int32{1}},
+		}
+
+		_, err := makeHashrings(receiver, "ingest-headless")
+		Expect(err).To(HaveOccurred())
+
+		errs := validateTenants(receiver, field.NewPath("spec", "tenants"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("spec.tenants[1].replicas"))
+	})
+
+	It("should not render a hashring ConfigMap for invalid tenants", func() {
+		receiver.Spec.Tenants = []thanosv1beta1.ReceiverTenant{
+			{Name: "team-a", Replicas: []int32{5}},
+		}
+		cm := &corev1.ConfigMap{}
+
+		Expect(makeHashringConfigMap(cm, receiver, "ingest-headless")).NotTo(Succeed())
+		Expect(cm.Data).To(BeEmpty())
+
+		receiver.Spec.Tenants[0].Replicas = []int32{1}
+		Expect(makeHashringConfigMap(cm, receiver, "ingest-headless")).To(Succeed())
+		var hashrings []hashring
+		Expect(json.Unmarshal([]byte(cm.Data[hashringsFile]), &hashrings)).To(Succeed())
+		Expect(hashrings).To(HaveLen(2))
+	})
+})
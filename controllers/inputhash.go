@@ -0,0 +1,194 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// inputHashAnnotation holds the hash of the Secrets and ConfigMaps read by
+// the pods of a component, so that changing them rolls the pods.
+const inputHashAnnotation = "thanos.orangesys.io/input-hash"
+
+// objstoreSecrets returns the Secrets holding the object storage
+// configuration or credentials of the named component, followed by secrets.
+// The credentials of typed object storage are listed along with the Secret
+// generated from them, which is rewritten when they change.
+func objstoreSecrets(
+	name string,
+	config *corev1.SecretKeySelector,
+	storage *thanosv1beta1.ObjectStorage,
+	secretName string,
+	secrets []string,
+) []string {
+	var names []string
+	if selector := objstoreConfig(name, config, storage); selector != nil {
+		names = append(names, selector.Name)
+		if config == nil {
+			names = append(names, objstoreCredentialSecrets(storage)...)
+		}
+	} else if secretName != "" {
+		names = append(names, secretName)
+	}
+	return append(names, secrets...)
+}
+
+// objstoreCredentialSecrets returns the Secrets selected by the credentials
+// of storage
+func objstoreCredentialSecrets(storage *thanosv1beta1.ObjectStorage) []string {
+	var selectors []*corev1.SecretKeySelector
+	switch {
+	case storage.GCS != nil:
+		selectors = append(selectors, storage.GCS.ServiceAccount)
+	case storage.S3 != nil:
+		selectors = append(selectors, storage.S3.AccessKey, storage.S3.SecretKey)
+	case storage.Azure != nil:
+		selectors = append(selectors, storage.Azure.StorageAccountKey)
+	}
+
+	var names []string
+	for _, selector := range selectors {
+		if selector != nil && !containsString(names, selector.Name) {
+			names = append(names, selector.Name)
+		}
+	}
+	return names
+}
+
+// querierSecrets returns the Secrets read by the pods of t
+func querierSecrets(t thanosv1beta1.Querier) []string {
+	return t.Spec.Secrets
+}
+
+// storeSecrets returns the Secrets read by the pods of t
+func storeSecrets(t thanosv1beta1.Store) []string {
+	return objstoreSecrets(
+		t.Name,
+		t.Spec.ObjectStorageConfig,
+		t.Spec.ObjectStorage,
+		t.Spec.SecretName,
+		t.Spec.Secrets,
+	)
+}
+
+// receiverSecrets returns the Secrets read by the pods of t
+func receiverSecrets(t thanosv1beta1.Receiver) []string {
+	return objstoreSecrets(
+		t.Name,
+		t.Spec.ObjectStorageConfig,
+		t.Spec.ObjectStorage,
+		t.Spec.SecretName,
+		t.Spec.Secrets,
+	)
+}
+
+// compactorSecrets returns the Secrets read by the pods of t
+func compactorSecrets(t thanosv1beta1.Compactor) []string {
+	return objstoreSecrets(
+		t.Name,
+		t.Spec.ObjectStorageConfig,
+		t.Spec.ObjectStorage,
+		t.Spec.SecretName,
+		t.Spec.Secrets,
+	)
+}
+
+// rulerSecrets returns the Secrets read by the pods of t
+func rulerSecrets(t thanosv1beta1.Ruler) []string {
+	return objstoreSecrets(
+		t.Name,
+		t.Spec.ObjectStorageConfig,
+		t.Spec.ObjectStorage,
+		t.Spec.SecretName,
+		t.Spec.Secrets,
+	)
+}
+
+// containsString tells whether s is one of list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// hashInputs returns a stable checksum of the named Secrets of namespace and
+// of configMaps, generated ConfigMaps which are hashed as given rather than
+// read back from the cache. Missing Secrets only contribute their name, their
+// creation then changes the hash.
+func hashInputs(
+	ctx context.Context,
+	c client.Reader,
+	namespace string,
+	secrets []string,
+	configMaps ...*corev1.ConfigMap,
+) (string, error) {
+	names := append([]string(nil), secrets...)
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "secret\x00%s\x00", name)
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret)
+		if err != nil {
+			if ignoreNotFound(err) == nil {
+				continue
+			}
+			return "", err
+		}
+		keys := make([]string, 0, len(secret.Data))
+		for k := range secret.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s\x00%s\x00", k, secret.Data[k])
+		}
+	}
+	for _, cm := range configMaps {
+		fmt.Fprintf(h, "configmap\x00%s\x00", cm.Name)
+		keys := make([]string, 0, len(cm.Data))
+		for k := range cm.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s\x00%s\x00", k, cm.Data[k])
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// setInputHash stamps hash on template, rolling its pods when it changes
+func setInputHash(template *corev1.PodTemplateSpec, hash string) {
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[inputHashAnnotation] = hash
+}
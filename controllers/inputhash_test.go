@@ -0,0 +1,127 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+var _ = Describe("Input hash", func() {
+	var secret *corev1.Secret
+
+	BeforeEach(func() {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "bucket-key",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{"key.json": []byte("v1")},
+		}
+	})
+
+	It("should be stable while the inputs do not change", func() {
+		c := fake.NewFakeClient(secret)
+		rules := &corev1.ConfigMap{Data: map[string]string{"a": "1", "b": "2"}}
+
+		first, err := hashInputs(context.Background(), c, "default", []string{"bucket-key", "missing"}, rules)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := hashInputs(context.Background(), c, "default", []string{"missing", "bucket-key"}, rules)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(second).To(Equal(first))
+	})
+
+	It("should change when a referenced Secret is rotated", func() {
+		c := fake.NewFakeClient(secret)
+		before, err := hashInputs(context.Background(), c, "default", []string{"bucket-key"})
+		Expect(err).NotTo(HaveOccurred())
+
+		secret.Data["key.json"] = []byte("v2")
+		Expect(c.Update(context.Background(), secret)).To(Succeed())
+		after, err := hashInputs(context.Background(), c, "default", []string{"bucket-key"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(after).NotTo(Equal(before))
+	})
+
+	It("should list the Secrets read by a component", func() {
+		store := thanosv1beta1.Store{
+			ObjectMeta: metav1.ObjectMeta{Name: "store"},
+			Spec: thanosv1beta1.StoreSpec{
+				SecretName: "bucket-key",
+				Secrets:    []string{"ca-bundle"},
+			},
+		}
+		Expect(storeSecrets(store)).To(Equal([]string{"bucket-key", "ca-bundle"}))
+
+		store.Spec.ObjectStorage = &thanosv1beta1.ObjectStorage{}
+		Expect(storeSecrets(store)).To(Equal([]string{"store-objstore", "ca-bundle"}))
+	})
+
+	It("should list the credentials of typed object storage", func() {
+		key := func(name, key string) *corev1.SecretKeySelector {
+			return &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				Key:                  key,
+			}
+		}
+		receiver := thanosv1beta1.Receiver{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingest"},
+			Spec: thanosv1beta1.ReceiverSpec{
+				Secrets: []string{"ca-bundle"},
+				ObjectStorage: &thanosv1beta1.ObjectStorage{
+					S3: &thanosv1beta1.S3ObjectStorage{
+						Bucket:    "metrics",
+						AccessKey: key("s3-credentials", "access-key"),
+						SecretKey: key("s3-credentials", "secret-key"),
+					},
+				},
+			},
+		}
+		Expect(receiverSecrets(receiver)).To(Equal([]string{"ingest-objstore", "s3-credentials", "ca-bundle"}))
+
+		receiver.Spec.ObjectStorage = &thanosv1beta1.ObjectStorage{
+			GCS: &thanosv1beta1.GCSObjectStorage{
+				Bucket:         "metrics",
+				ServiceAccount: key("gcs-credentials", "key.json"),
+			},
+		}
+		Expect(receiverSecrets(receiver)).To(Equal([]string{"ingest-objstore", "gcs-credentials", "ca-bundle"}))
+
+		receiver.Spec.ObjectStorage = &thanosv1beta1.ObjectStorage{
+			Azure: &thanosv1beta1.AzureObjectStorage{
+				StorageAccount:    "metrics",
+				StorageAccountKey: key("azure-credentials", "key"),
+				Container:         "blocks",
+			},
+		}
+		Expect(receiverSecrets(receiver)).To(Equal([]string{"ingest-objstore", "azure-credentials", "ca-bundle"}))
+
+		// a raw configuration takes precedence over typed object storage
+		receiver.Spec.ObjectStorageConfig = key("objstore", "objstore.yml")
+		Expect(receiverSecrets(receiver)).To(Equal([]string{"objstore", "ca-bundle"}))
+	})
+})
@@ -0,0 +1,335 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+const (
+	objstoreDir  = "/etc/thanos/objstore/"
+	objstoreFile = "objstore.yaml"
+)
+
+// bucketConfig mirrors the objstore configuration read by thanos
+type bucketConfig struct {
+	Type   string      `json:"type"`
+	Config interface{} `json:"config"`
+}
+
+type gcsConfig struct {
+	Bucket         string `json:"bucket"`
+	ServiceAccount string `json:"service_account,omitempty"`
+}
+
+type s3Config struct {
+	Bucket            string `json:"bucket"`
+	Endpoint          string `json:"endpoint"`
+	Region            string `json:"region,omitempty"`
+	AccessKey         string `json:"access_key,omitempty"`
+	SecretKey         string `json:"secret_key,omitempty"`
+	Insecure          bool   `json:"insecure,omitempty"`
+	SignatureVersion2 bool   `json:"signature_version2,omitempty"`
+	EncryptSSE        bool   `json:"encrypt_sse,omitempty"`
+}
+
+type azureConfig struct {
+	StorageAccount    string `json:"storage_account"`
+	StorageAccountKey string `json:"storage_account_key"`
+	Container         string `json:"container"`
+	Endpoint          string `json:"endpoint,omitempty"`
+}
+
+type filesystemConfig struct {
+	Directory string `json:"directory"`
+}
+
+// objstoreSecretName returns the name of the Secret generated from the typed
+// object storage of the named component
+func objstoreSecretName(name string) string {
+	return name + "-objstore"
+}
+
+// objstoreConfig returns the secret key holding the objstore configuration of
+// the named component: the raw config if given, else the generated Secret
+// when typed object storage is used, else nil.
+func objstoreConfig(
+	name string,
+	config *corev1.SecretKeySelector,
+	storage *thanosv1beta1.ObjectStorage,
+) *corev1.SecretKeySelector {
+	if config != nil || storage == nil {
+		return config
+	}
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{
+			Name: objstoreSecretName(name),
+		},
+		Key: objstoreFile,
+	}
+}
+
+// secretKeyValue returns the value selected by sel in namespace. A nil
+// selector yields an empty value.
+func secretKeyValue(ctx context.Context, c client.Reader, namespace string, sel *corev1.SecretKeySelector) (string, error) {
+	if sel == nil {
+		return "", nil
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: sel.Name, Namespace: namespace}, secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[sel.Key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret %s/%s", sel.Key, namespace, sel.Name)
+	}
+	return string(value), nil
+}
+
+// renderObjstore renders storage into a thanos objstore configuration,
+// resolving credentials from secrets in namespace.
+func renderObjstore(
+	ctx context.Context,
+	c client.Reader,
+	namespace string,
+	storage *thanosv1beta1.ObjectStorage,
+) ([]byte, error) {
+	var configs []bucketConfig
+
+	if gcs := storage.GCS; gcs != nil {
+		serviceAccount, err := secretKeyValue(ctx, c, namespace, gcs.ServiceAccount)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, bucketConfig{
+			Type: "GCS",
+			Config: gcsConfig{
+				Bucket:         gcs.Bucket,
+				ServiceAccount: serviceAccount,
+			},
+		})
+	}
+	if s3 := storage.S3; s3 != nil {
+		accessKey, err := secretKeyValue(ctx, c, namespace, s3.AccessKey)
+		if err != nil {
+			return nil, err
+		}
+		secretKey, err := secretKeyValue(ctx, c, namespace, s3.SecretKey)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, bucketConfig{
+			Type: "S3",
+			Config: s3Config{
+				Bucket:            s3.Bucket,
+				Endpoint:          s3.Endpoint,
+				Region:            s3.Region,
+				AccessKey:         accessKey,
+				SecretKey:         secretKey,
+				Insecure:          s3.Insecure,
+				SignatureVersion2: s3.SignatureVersion2,
+				EncryptSSE:        s3.EncryptSSE,
+			},
+		})
+	}
+	if azure := storage.Azure; azure != nil {
+		storageAccountKey, err := secretKeyValue(ctx, c, namespace, azure.StorageAccountKey)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, bucketConfig{
+			Type: "AZURE",
+			Config: azureConfig{
+				StorageAccount:    azure.StorageAccount,
+				StorageAccountKey: storageAccountKey,
+				Container:         azure.Container,
+				Endpoint:          azure.Endpoint,
+			},
+		})
+	}
+	if fs := storage.Filesystem; fs != nil {
+		configs = append(configs, bucketConfig{
+			Type: "FILESYSTEM",
+			Config: filesystemConfig{
+				Directory: fs.Directory,
+			},
+		})
+	}
+
+	if len(configs) != 1 {
+		return nil, fmt.Errorf("exactly one of gcs, s3, azure or filesystem must be set in objectStorage, got %d", len(configs))
+	}
+	return yaml.Marshal(configs[0])
+}
+
+// reconcileObjstoreSecret renders the typed object storage of owner into its
+// generated Secret. The Secret is removed once owner stops using typed object
+// storage, unless something else created it.
+func reconcileObjstoreSecret(
+	ctx context.Context,
+	c client.Client,
+	scheme *runtime.Scheme,
+	owner metav1.Object,
+	config *corev1.SecretKeySelector,
+	storage *thanosv1beta1.ObjectStorage,
+) error {
+	secret := &corev1.Secret{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      objstoreSecretName(owner.GetName()),
+			Namespace: owner.GetNamespace(),
+		},
+	}
+
+	if config != nil || storage == nil {
+		err := c.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, secret)
+		if err != nil {
+			return ignoreNotFound(err)
+		}
+		if !metav1.IsControlledBy(secret, owner) {
+			return nil
+		}
+		return ignoreNotFound(c.Delete(ctx, secret))
+	}
+
+	data, err := renderObjstore(ctx, c, owner.GetNamespace(), storage)
+	if err != nil {
+		return err
+	}
+	_, err = ctrl.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.Labels = map[string]string{
+			"thanos": owner.GetName(),
+		}
+		secret.Data = map[string][]byte{
+			objstoreFile: data,
+		}
+		return controllerutil.SetControllerReference(owner, secret, scheme)
+	})
+	return err
+}
+
+// objstore holds what a thanos container needs to reach its bucket
+type objstore struct {
+	Args         []string
+	Env          []corev1.EnvVar
+	Volumes      []corev1.Volume
+	VolumeMounts []corev1.VolumeMount
+}
+
+// makeObjstore wires a component to its bucket. config, a secret key holding
+// a complete thanos objstore configuration, works with every provider and
+// takes precedence over the deprecated GCS-only objstoreType, bucketName and
+// secretName fields. It is an error for neither to be set.
+func makeObjstore(
+	config *corev1.SecretKeySelector,
+	objstoreType string,
+	bucketName string,
+	secretName string,
+) (objstore, error) {
+	if config != nil {
+		return objstore{
+			Args: []string{
+				fmt.Sprintf("--objstore.config-file=%s%s", objstoreDir, config.Key),
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "objstore-config",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: config.Name,
+							Items: []corev1.KeyToPath{
+								{
+									Key:  config.Key,
+									Path: config.Key,
+								},
+							},
+						},
+					},
+				},
+			},
+			VolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      "objstore-config",
+					MountPath: objstoreDir,
+					ReadOnly:  true,
+				},
+			},
+		}, nil
+	}
+
+	if bucketName == "" {
+		return objstore{}, fmt.Errorf("object storage is not configured: set objectStorage, objectStorageConfig or bucketName")
+	}
+	if objstoreType != "" && objstoreType != "GCS" {
+		return objstore{}, fmt.Errorf("objstoreType %s is not supported: configure it with objectStorage", objstoreType)
+	}
+	data, err := yaml.Marshal(bucketConfig{
+		Type:   "GCS",
+		Config: gcsConfig{Bucket: bucketName},
+	})
+	if err != nil {
+		return objstore{}, err
+	}
+	obs := objstore{
+		Args: []string{
+			"--objstore.config=" + strings.TrimSuffix(string(data), "\n"),
+		},
+	}
+	// Without a key, the bucket is reached with the credentials of the node
+	if secretName == "" {
+		return obs, nil
+	}
+
+	// Need create json from gcp iam
+	// https://github.com/orangesys/blueprint/tree/master/prometheus-thanos
+	// kubectl create secret generic ${SERVICE_ACCOUNT_NAME} --from-file=${SERVICE_ACCOUNT_NAME}.json=${SERVICE_ACCOUNT_NAME}.json
+	obs.Env = []corev1.EnvVar{
+		{
+			Name:  "GOOGLE_APPLICATION_CREDENTIALS",
+			Value: secretsDir + secretName + ".json",
+		},
+	}
+	obs.Volumes = []corev1.Volume{
+		{
+			Name: "google-cloud-key",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secretName,
+				},
+			},
+		},
+	}
+	obs.VolumeMounts = []corev1.VolumeMount{
+		{
+			Name:      "google-cloud-key",
+			MountPath: secretsDir,
+		},
+	}
+	return obs, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+var _ = Describe("Object storage", func() {
+	Context("makeObjstore", func() {
+		It("should mount a raw configuration", func() {
+			obs, err := makeObjstore(&corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "objstore"},
+				Key:                  "bucket.yaml",
+			}, "GCS", "ignored", "ignored")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(obs.Args).To(Equal([]string{"--objstore.config-file=/etc/thanos/objstore/bucket.yaml"}))
+			Expect(obs.Volumes).To(HaveLen(1))
+			Expect(obs.Volumes[0].Secret.SecretName).To(Equal("objstore"))
+			Expect(obs.Env).To(BeEmpty())
+		})
+
+		It("should render the deprecated fields as YAML", func() {
+			obs, err := makeObjstore(nil, "GCS", `metrics: "eu"`, "gcs-key")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(obs.Args).To(Equal([]string{`--objstore.config=config:
+  bucket: 'metrics: "eu"'
+type: GCS`}))
+			Expect(obs.Env).To(Equal([]corev1.EnvVar{{
+				Name:  "GOOGLE_APPLICATION_CREDENTIALS",
+				Value: secretsDir + "gcs-key.json",
+			}}))
+			Expect(obs.Volumes).To(HaveLen(1))
+			Expect(obs.Volumes[0].Secret.SecretName).To(Equal("gcs-key"))
+		})
+
+		It("should use the node credentials without a secret", func() {
+			obs, err := makeObjstore(nil, "", "metrics", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(obs.Args).To(Equal([]string{"--objstore.config=config:\n  bucket: metrics\ntype: GCS"}))
+			Expect(obs.Env).To(BeEmpty())
+			Expect(obs.Volumes).To(BeEmpty())
+			Expect(obs.VolumeMounts).To(BeEmpty())
+		})
+
+		It("should refuse components without object storage", func() {
+			_, err := makeObjstore(nil, "", "", "")
+			Expect(err).To(HaveOccurred())
+
+			compactor := thanosv1beta1.Compactor{ObjectMeta: metav1.ObjectMeta{Name: "compactor"}}
+			Expect(setCompactorStatefulSet(&appsv1.StatefulSet{}, &corev1.Service{}, nil, compactor)).NotTo(Succeed())
+			Expect(errorFields(validateCompactor(&compactor))).To(ContainElement("spec.objectStorage"))
+		})
+	})
+
+	Context("renderObjstore", func() {
+		It("should resolve the credentials of typed object storage", func() {
+			c := fake.NewFakeClient(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "s3-credentials", Namespace: "monitoring"},
+				Data: map[string][]byte{
+					"access-key": []byte("AKIA"),
+					"secret-key": []byte("s3cr3t"),
+				},
+			})
+			key := func(key string) *corev1.SecretKeySelector {
+				return &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "s3-credentials"},
+					Key:                  key,
+				}
+			}
+
+			data, err := renderObjstore(context.Background(), c, "monitoring", &thanosv1beta1.ObjectStorage{
+				S3: &thanosv1beta1.S3ObjectStorage{
+					Bucket:    "metrics",
+					Endpoint:  "s3.eu-west-1.amazonaws.com",
+					AccessKey: key("access-key"),
+					SecretKey: key("secret-key"),
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(data)).To(Equal(`config:
+  access_key: AKIA
+  bucket: metrics
+  endpoint: s3.eu-west-1.amazonaws.com
+  secret_key: s3cr3t
+type: S3
+`))
+		})
+	})
+
+	Context("validateObjstore", func() {
+		It("should accept any of the ways to configure a bucket", func() {
+			spec := field.NewPath("spec")
+			config := &corev1.SecretKeySelector{Key: "objstore.yaml"}
+			storage := &thanosv1beta1.ObjectStorage{
+				Filesystem: &thanosv1beta1.FilesystemObjectStorage{Directory: "/data"},
+			}
+
+			Expect(validateObjstore(config, nil, "", "", spec)).To(BeEmpty())
+			Expect(validateObjstore(nil, storage, "", "", spec)).To(BeEmpty())
+			Expect(validateObjstore(nil, nil, "GCS", "metrics", spec)).To(BeEmpty())
+			Expect(errorFields(validateObjstore(nil, nil, "", "", spec))).To(ConsistOf("spec.objectStorage"))
+		})
+
+		It("should reject the deprecated fields for other providers than GCS", func() {
+			spec := field.NewPath("spec")
+
+			Expect(errorFields(validateObjstore(nil, nil, "S3", "metrics", spec))).To(ConsistOf("spec.objstoreType"))
+
+			_, err := makeObjstore(nil, "S3", "metrics", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
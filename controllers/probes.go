@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/version"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+const (
+	livenessPath  = "/-/healthy"
+	readinessPath = "/-/ready"
+)
+
+// probesVersion is the first thanos release serving the probe endpoints on
+// every component
+var probesVersion = version.MustParseSemantic("v0.8.0")
+
+// makeProbes returns the liveness and readiness probes of a thanos container
+// running image and serving its http port, tuned by probes. The liveness
+// probe tolerates ten minutes of failures so slow starts, such as a store
+// syncing a large bucket, are not killed; readiness keeps them out of their
+// Services. Releases without the probe endpoints get no probes.
+func makeProbes(image string, probes *thanosv1beta1.Probes) (liveness, readiness *corev1.Probe) {
+	if !imageAtLeast(image, probesVersion) {
+		return nil, nil
+	}
+	liveness = &corev1.Probe{
+		Handler:          httpGetHandler(livenessPath),
+		TimeoutSeconds:   3,
+		PeriodSeconds:    5,
+		FailureThreshold: 120,
+	}
+	readiness = &corev1.Probe{
+		Handler:          httpGetHandler(readinessPath),
+		TimeoutSeconds:   3,
+		PeriodSeconds:    5,
+		FailureThreshold: 3,
+	}
+	if probes != nil {
+		tuneProbe(liveness, probes.Liveness)
+		tuneProbe(readiness, probes.Readiness)
+	}
+	return liveness, readiness
+}
+
+func httpGetHandler(path string) corev1.Handler {
+	return corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: path,
+			Port: intstr.FromString("http"),
+		},
+	}
+}
+
+// tuneProbe overrides the timings of probe set in settings
+func tuneProbe(probe *corev1.Probe, settings *thanosv1beta1.ProbeSettings) {
+	if settings == nil {
+		return
+	}
+	if settings.InitialDelaySeconds != nil {
+		probe.InitialDelaySeconds = *settings.InitialDelaySeconds
+	}
+	if settings.TimeoutSeconds != nil {
+		probe.TimeoutSeconds = *settings.TimeoutSeconds
+	}
+	if settings.PeriodSeconds != nil {
+		probe.PeriodSeconds = *settings.PeriodSeconds
+	}
+	if settings.SuccessThreshold != nil {
+		probe.SuccessThreshold = *settings.SuccessThreshold
+	}
+	if settings.FailureThreshold != nil {
+		probe.FailureThreshold = *settings.FailureThreshold
+	}
+}
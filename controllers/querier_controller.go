@@ -18,15 +18,22 @@ package controllers
 
 import (
 	"context"
+	"sort"
 
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 
 	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
@@ -40,14 +47,19 @@ type QuerierReconciler struct {
 	Log      logr.Logger
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
+	// DefaultResources are the requests given to containers which leave them out
+	DefaultResources corev1.ResourceList
 }
 
 // +kubebuilder:rbac:groups=thanos.orangesys.io,resources=queriers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=thanos.orangesys.io,resources=queriers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=stores;receivers;sidecars,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=apps,resources=deployment,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 
-func (r *QuerierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *QuerierReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("querier", req.NamespacedName)
 
@@ -61,6 +73,17 @@ func (r *QuerierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	// Record failed reconciliations in the status
+	defer func() {
+		if err == nil {
+			return
+		}
+		querier.Status.Conditions = setReconcileErrorCondition(querier.Status.Conditions, querier.Generation, err)
+		if err := r.Status().Update(ctx, querier); err != nil {
+			log.Error(err, "unable to record reconcile error")
+		}
+	}()
+
 	// Generate Service
 	service := &corev1.Service{
 		ObjectMeta: ctrl.ObjectMeta{
@@ -68,14 +91,49 @@ func (r *QuerierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			Namespace: req.Namespace,
 		},
 	}
-	_, err := ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
-		makeService(service, service.Name)
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
+		makeService(service, componentQuerier, querier.Name, querier.Spec.ServiceMetadata)
 		return controllerutil.SetControllerReference(querier, service, r.Scheme)
 	})
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// Collect selected StoreAPI Services
+	stores, err := r.selectStores(ctx, querier)
+	if err != nil {
+		log.Error(err, "unable to list StoreAPI components")
+		return ctrl.Result{}, err
+	}
+
+	// Generate file SD ConfigMap, only needed in fileSD mode
+	storeSD := &corev1.ConfigMap{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      storeSDConfigMapName(*querier),
+			Namespace: req.Namespace,
+		},
+	}
+	if querier.Spec.FileSD {
+		_, err = ctrl.CreateOrUpdate(ctx, r.Client, storeSD, func() error {
+			if err := makeStoreSDConfigMap(storeSD, *querier, stores); err != nil {
+				return err
+			}
+			return controllerutil.SetControllerReference(querier, storeSD, r.Scheme)
+		})
+	} else {
+		err = ignoreNotFound(r.Delete(ctx, storeSD))
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Hash the inputs of the pods, rolling them when one changes
+	inputHash, err := hashInputs(ctx, r.Client, req.Namespace, querierSecrets(*querier))
+	if err != nil {
+		log.Error(err, "unable to hash pod inputs")
+		return ctrl.Result{}, err
+	}
+
 	// Generate Deployment
 	dm := &appsv1.Deployment{
 		ObjectMeta: ctrl.ObjectMeta{
@@ -85,11 +143,16 @@ func (r *QuerierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	}
 
 	_, err = ctrl.CreateOrUpdate(ctx, r.Client, dm, func() error {
-		setQuerierDeployment(
+		if err := setQuerierDeployment(
 			dm,
 			service,
+			r.DefaultResources,
+			stores,
 			*querier,
-		)
+		); err != nil {
+			return err
+		}
+		setInputHash(&dm.Spec.Template, inputHash)
 		return controllerutil.SetControllerReference(querier, dm, r.Scheme)
 	})
 
@@ -101,10 +164,15 @@ func (r *QuerierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	dmNN := req.NamespacedName
 	dmNN.Name = dm.Name
 	if err := r.Get(ctx, dmNN, dm); err != nil {
-		log.Error(err, "unable to fetch StatusfulSet", "namespaceName", dmNN)
+		log.Error(err, "unable to fetch Deployment", "namespaceName", dmNN)
 		return ctrl.Result{}, err
 	}
 	querier.Status.DeploymentStatus = dm.Status
+	querier.Status.Replicas = dm.Status.Replicas
+	querier.Status.UpdatedReplicas = dm.Status.UpdatedReplicas
+	querier.Status.AvailableReplicas = dm.Status.AvailableReplicas
+	querier.Status.UnavailableReplicas = dm.Status.UnavailableReplicas
+	querier.Status.Selector = metav1.FormatLabelSelector(dm.Spec.Selector)
 
 	serviceNN := req.NamespacedName
 	serviceNN.Name = service.Name
@@ -115,6 +183,17 @@ func (r *QuerierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	querier.Status.ServiceStatus = service.Status
 
+	querier.Status.ObservedGeneration = querier.Generation
+	querier.Status.Conditions = setWorkloadConditions(
+		querier.Status.Conditions,
+		querier.Generation,
+		dm.Status.ObservedGeneration >= dm.Generation,
+		*dm.Spec.Replicas,
+		dm.Status.UpdatedReplicas,
+		dm.Status.AvailableReplicas,
+	)
+	querier.Status.Conditions = setReconcileErrorCondition(querier.Status.Conditions, querier.Generation, nil)
+
 	err = r.Status().Update(ctx, querier)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -123,10 +202,116 @@ func (r *QuerierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
+// selectStores returns the names of the headless Services listing the pods of
+// every Store, Receiver and Sidecar matching the querier's storeSelector,
+// sorted. A nil selector selects nothing.
+func (r *QuerierReconciler) selectStores(ctx context.Context, querier *thanosv1beta1.Querier) ([]string, error) {
+	if querier.Spec.StoreSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(querier.Spec.StoreSelector)
+	if err != nil {
+		return nil, err
+	}
+	opts := client.UseListOptions(&client.ListOptions{
+		Namespace:     querier.Namespace,
+		LabelSelector: selector,
+	})
+
+	var stores []string
+	storeList := &thanosv1beta1.StoreList{}
+	if err := r.List(ctx, storeList, opts); err != nil {
+		return nil, err
+	}
+	for _, store := range storeList.Items {
+		stores = append(stores, headlessServiceName(store.Name))
+	}
+	receiverList := &thanosv1beta1.ReceiverList{}
+	if err := r.List(ctx, receiverList, opts); err != nil {
+		return nil, err
+	}
+	for _, receiver := range receiverList.Items {
+		stores = append(stores, headlessServiceName(receiver.Name))
+	}
+	sidecarList := &thanosv1beta1.SidecarList{}
+	if err := r.List(ctx, sidecarList, opts); err != nil {
+		return nil, err
+	}
+	for _, sidecar := range sidecarList.Items {
+		stores = append(stores, sidecar.Name)
+	}
+
+	sort.Strings(stores)
+	return stores, nil
+}
+
+// queriersForStore maps a Store, Receiver or Sidecar event to every Querier
+// in the same namespace whose storeSelector matches it.
+func (r *QuerierReconciler) queriersForStore(obj handler.MapObject) []reconcile.Request {
+	queriers := &thanosv1beta1.QuerierList{}
+	if err := r.List(context.Background(), queriers, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list queriers", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, querier := range queriers.Items {
+		if querier.Spec.StoreSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(querier.Spec.StoreSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(obj.Meta.GetLabels())) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      querier.Name,
+					Namespace: querier.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+// queriersForSecret maps a Secret event to every Querier in the same namespace
+// whose pods read it.
+func (r *QuerierReconciler) queriersForSecret(obj handler.MapObject) []reconcile.Request {
+	queriers := &thanosv1beta1.QuerierList{}
+	if err := r.List(context.Background(), queriers, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list queriers", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, querier := range queriers.Items {
+		if containsString(querierSecrets(querier), obj.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      querier.Name,
+					Namespace: querier.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
 func (r *QuerierReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	toQueriers := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.queriersForStore),
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&thanosv1beta1.Querier{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}). // Generates file SD ConfigMaps
+		Watches(&source.Kind{Type: &thanosv1beta1.Store{}}, toQueriers).
+		Watches(&source.Kind{Type: &thanosv1beta1.Receiver{}}, toQueriers).
+		Watches(&source.Kind{Type: &thanosv1beta1.Sidecar{}}, toQueriers).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.queriersForSecret),
+		}).
 		Complete(r)
 }
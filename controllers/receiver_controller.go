@@ -22,11 +22,16 @@ import (
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 
 	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
@@ -40,14 +45,19 @@ type ReceiverReconciler struct {
 	Log      logr.Logger
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
+	// DefaultResources are the requests given to containers which leave them out
+	DefaultResources corev1.ResourceList
 }
 
 // +kubebuilder:rbac:groups=thanos.orangesys.io,resources=receivers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=thanos.orangesys.io,resources=receivers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 
-func (r *ReceiverReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *ReceiverReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("receiver", req.NamespacedName)
 
@@ -61,6 +71,23 @@ func (r *ReceiverReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	// Record failed reconciliations in the status
+	defer func() {
+		if err == nil {
+			return
+		}
+		receiver.Status.Conditions = setReconcileErrorCondition(receiver.Status.Conditions, receiver.Generation, err)
+		if err := r.Status().Update(ctx, receiver); err != nil {
+			log.Error(err, "unable to record reconcile error")
+		}
+	}()
+
+	// Generate object storage Secret
+	if err := reconcileObjstoreSecret(ctx, r.Client, r.Scheme, receiver, receiver.Spec.ObjectStorageConfig, receiver.Spec.ObjectStorage); err != nil {
+		log.Error(err, "unable to generate object storage Secret")
+		return ctrl.Result{}, err
+	}
+
 	// Generate Service
 	service := &corev1.Service{
 		ObjectMeta: ctrl.ObjectMeta{
@@ -68,29 +95,107 @@ func (r *ReceiverReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			Namespace: req.Namespace,
 		},
 	}
-	_, err := ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
 		// util.SetReceiverService(service, *receiver)
-		makeService(service, receiver.Name)
+		makeService(service, componentReceiver, receiver.Name, receiver.Spec.ServiceMetadata)
 		return controllerutil.SetControllerReference(receiver, service, r.Scheme)
 	})
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	// Generate StatefulSet
+	// Generate the headless Service governing the StatefulSet
+	governing := &corev1.Service{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      headlessServiceName(req.Name),
+			Namespace: req.Namespace,
+		},
+	}
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, governing, func() error {
+		if err := refuseAdoption(governing, receiver); err != nil {
+			return err
+		}
+		makeHeadlessService(governing, componentReceiver, receiver.Name)
+		return controllerutil.SetControllerReference(receiver, governing, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	serviceName := governing.Name
+
+	// The hashrings address the pods through the governing Service of the
+	// StatefulSet, which is immutable. StatefulSets created by earlier
+	// versions use another one, under which the endpoints do not resolve:
+	// they are deleted leaving their pods and volumes to the StatefulSet
+	// replacing them, which rolls the pods onto the new endpoints.
 	ss := &appsv1.StatefulSet{
 		ObjectMeta: ctrl.ObjectMeta{
 			Name:      req.Name,
 			Namespace: req.Namespace,
 		},
 	}
+	if err := r.Get(ctx, req.NamespacedName, ss); ignoreNotFound(err) != nil {
+		log.Error(err, "unable to fetch StatefulSet", "namespaceName", req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+	if !ss.CreationTimestamp.IsZero() && ss.Spec.ServiceName != serviceName {
+		if ss.DeletionTimestamp == nil {
+			log.Info("recreating StatefulSet to change its governing Service", "from", ss.Spec.ServiceName, "to", serviceName)
+			r.Recorder.Eventf(receiver, corev1.EventTypeNormal, "RecreatingStatefulSet",
+				"Recreating StatefulSet %s governed by Service %s", ss.Name, ss.Spec.ServiceName)
+			err := r.Delete(ctx, ss, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+			if ignoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+	if err := r.deleteLegacyGoverningService(ctx, req.Namespace); err != nil {
+		log.Error(err, "unable to delete the legacy governing Service")
+		return ctrl.Result{}, err
+	}
+
+	// Generate hashring ConfigMap, only needed with several replicas or tenants
+	hashrings := &corev1.ConfigMap{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      hashringConfigMapName(*receiver),
+			Namespace: req.Namespace,
+		},
+	}
+	if receiverNeedsHashring(*receiver) {
+		_, err = ctrl.CreateOrUpdate(ctx, r.Client, hashrings, func() error {
+			if err := makeHashringConfigMap(hashrings, *receiver, serviceName); err != nil {
+				return err
+			}
+			return controllerutil.SetControllerReference(receiver, hashrings, r.Scheme)
+		})
+	} else {
+		err = ignoreNotFound(r.Delete(ctx, hashrings))
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Hash the inputs of the pods, rolling them when one changes. The
+	// hashring ConfigMap is left out: thanos receive watches the hashrings
+	// file and reloads it when the kubelet updates the volume.
+	inputHash, err := hashInputs(ctx, r.Client, req.Namespace, receiverSecrets(*receiver))
+	if err != nil {
+		log.Error(err, "unable to hash pod inputs")
+		return ctrl.Result{}, err
+	}
 
+	// Generate StatefulSet
 	_, err = ctrl.CreateOrUpdate(ctx, r.Client, ss, func() error {
-		setReceiverStatefulSet(
+		if err := setReceiverStatefulSet(
 			ss,
 			service,
+			r.DefaultResources,
 			*receiver,
-		)
+		); err != nil {
+			return err
+		}
+		setInputHash(&ss.Spec.Template, inputHash)
 		return controllerutil.SetControllerReference(receiver, ss, r.Scheme)
 	})
 
@@ -98,6 +203,17 @@ func (r *ReceiverReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
+	// Expand the existing volumes to the requested storage
+	claim, err := makeReceiverVolumeClaimTemplate(*receiver)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	pending, total, err := resizeVolumeClaims(ctx, r.Client, ss, claim)
+	if err != nil {
+		log.Error(err, "unable to resize PersistentVolumeClaims")
+		return ctrl.Result{}, err
+	}
+
 	// Update Status
 	ssNN := req.NamespacedName
 	ssNN.Name = ss.Name
@@ -106,6 +222,11 @@ func (r *ReceiverReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 	receiver.Status.StatefulSetStatus = ss.Status
+	receiver.Status.Replicas = ss.Status.Replicas
+	receiver.Status.UpdatedReplicas = ss.Status.UpdatedReplicas
+	receiver.Status.AvailableReplicas = ss.Status.ReadyReplicas
+	receiver.Status.UnavailableReplicas = ss.Status.Replicas - ss.Status.ReadyReplicas
+	receiver.Status.Selector = metav1.FormatLabelSelector(ss.Spec.Selector)
 
 	serviceNN := req.NamespacedName
 	serviceNN.Name = service.Name
@@ -115,18 +236,93 @@ func (r *ReceiverReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	}
 	receiver.Status.ServiceStatus = service.Status
 
+	receiver.Status.ObservedGeneration = receiver.Generation
+	receiver.Status.Conditions = setWorkloadConditions(
+		receiver.Status.Conditions,
+		receiver.Generation,
+		ss.Status.ObservedGeneration >= ss.Generation,
+		*ss.Spec.Replicas,
+		ss.Status.UpdatedReplicas,
+		ss.Status.ReadyReplicas,
+	)
+	storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	receiver.Status.Conditions = setStorageResizingCondition(
+		receiver.Status.Conditions,
+		receiver.Generation,
+		pending,
+		total,
+		storage.String(),
+	)
+	receiver.Status.Conditions = setReconcileErrorCondition(receiver.Status.Conditions, receiver.Generation, nil)
+
 	err = r.Status().Update(ctx, receiver)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if pending > 0 {
+		return ctrl.Result{RequeueAfter: volumeResizeRequeueAfter}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
+// deleteLegacyGoverningService deletes the headless Service the receivers of
+// namespace shared before each got its own, once no StatefulSet uses it.
+func (r *ReceiverReconciler) deleteLegacyGoverningService(ctx context.Context, namespace string) error {
+	service := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: legacyGoverningServiceName, Namespace: namespace}, service)
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+	// Leave alone Services the operator did not create
+	if len(service.OwnerReferences) > 0 || service.Labels["service"] != legacyGoverningServiceName {
+		return nil
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for _, ss := range statefulSets.Items {
+		if ss.Spec.ServiceName == legacyGoverningServiceName {
+			return nil
+		}
+	}
+	return ignoreNotFound(r.Delete(ctx, service))
+}
+
+// receiversForSecret maps a Secret event to every Receiver in the same namespace
+// whose pods read it.
+func (r *ReceiverReconciler) receiversForSecret(obj handler.MapObject) []reconcile.Request {
+	receivers := &thanosv1beta1.ReceiverList{}
+	if err := r.List(context.Background(), receivers, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list receivers", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, receiver := range receivers.Items {
+		if containsString(receiverSecrets(receiver), obj.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      receiver.Name,
+					Namespace: receiver.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
 func (r *ReceiverReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&thanosv1beta1.Receiver{}).
 		Owns(&appsv1.StatefulSet{}). // Generates StatefulSets
 		Owns(&corev1.Service{}).     // Generates Services
+		Owns(&corev1.Secret{}).      // Generates object storage Secrets
+		Owns(&corev1.ConfigMap{}).   // Generates hashrings
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.receiversForSecret),
+		}).
 		Complete(r)
 }
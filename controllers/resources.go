@@ -0,0 +1,66 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ParseResourceList parses a comma separated list of name=quantity pairs,
+// such as "cpu=100m,memory=1Gi".
+func ParseResourceList(s string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	if s == "" {
+		return list, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid resource %q, want name=quantity", pair)
+		}
+		quantity, err := resource.ParseQuantity(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity for resource %q: %v", kv[0], err)
+		}
+		list[corev1.ResourceName(kv[0])] = quantity
+	}
+	return list, nil
+}
+
+// defaultResources returns resources with the requests it leaves out taken
+// from defaults. A default above the matching limit is capped to the limit so
+// the container stays valid.
+func defaultResources(resources corev1.ResourceRequirements, defaults corev1.ResourceList) corev1.ResourceRequirements {
+	resources = *resources.DeepCopy()
+	for name, request := range defaults {
+		if _, found := resources.Requests[name]; found {
+			continue
+		}
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		if limit, found := resources.Limits[name]; found && limit.Cmp(request) <= 0 {
+			request = limit
+		}
+		resources.Requests[name] = request.DeepCopy()
+	}
+	return resources
+}
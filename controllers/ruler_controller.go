@@ -0,0 +1,315 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// RulerReconciler reconciles a Ruler object
+type RulerReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+	// DefaultResources are the requests given to containers which leave them out
+	DefaultResources corev1.ResourceList
+}
+
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=rulers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=rulers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=queriers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+
+func (r *RulerReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("ruler", req.NamespacedName)
+
+	// Fetch the ruler instance
+	ruler := &thanosv1beta1.Ruler{}
+	if err := r.Get(ctx, req.NamespacedName, ruler); err != nil {
+		if ignoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch thanos ruler")
+		return ctrl.Result{}, err
+	}
+
+	// Record failed reconciliations in the status
+	defer func() {
+		if err == nil {
+			return
+		}
+		ruler.Status.Conditions = setReconcileErrorCondition(ruler.Status.Conditions, ruler.Generation, err)
+		if err := r.Status().Update(ctx, ruler); err != nil {
+			log.Error(err, "unable to record reconcile error")
+		}
+	}()
+
+	// Make sure the referenced querier exists before pointing --query at it
+	if ruler.Spec.QuerierRef != nil {
+		querierNN := req.NamespacedName
+		querierNN.Name = ruler.Spec.QuerierRef.Name
+		if err := r.Get(ctx, querierNN, &thanosv1beta1.Querier{}); err != nil {
+			log.Error(err, "unable to fetch referenced Querier", "namespaceName", querierNN)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Collect selected rule ConfigMaps
+	sources, err := r.selectRuleConfigMaps(ctx, ruler)
+	if err != nil {
+		log.Error(err, "unable to list rule ConfigMaps")
+		return ctrl.Result{}, err
+	}
+
+	// Generate rules ConfigMap
+	rules := &corev1.ConfigMap{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      ruler.Name + "-rules",
+			Namespace: req.Namespace,
+		},
+	}
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, rules, func() error {
+		makeRuleConfigMap(rules, ruler.Name, sources)
+		return controllerutil.SetControllerReference(ruler, rules, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Generate object storage Secret
+	if err := reconcileObjstoreSecret(ctx, r.Client, r.Scheme, ruler, ruler.Spec.ObjectStorageConfig, ruler.Spec.ObjectStorage); err != nil {
+		log.Error(err, "unable to generate object storage Secret")
+		return ctrl.Result{}, err
+	}
+
+	// Generate Service
+	service := &corev1.Service{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+	}
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
+		makeService(service, componentRuler, ruler.Name, ruler.Spec.ServiceMetadata)
+		return controllerutil.SetControllerReference(ruler, service, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Hash the inputs of the pods, rolling them when one changes
+	inputHash, err := hashInputs(ctx, r.Client, req.Namespace, rulerSecrets(*ruler), rules)
+	if err != nil {
+		log.Error(err, "unable to hash pod inputs")
+		return ctrl.Result{}, err
+	}
+
+	// Generate StatefulSet
+	ss := &appsv1.StatefulSet{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+	}
+
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, ss, func() error {
+		if err := setRulerStatefulSet(
+			ss,
+			service,
+			r.DefaultResources,
+			rules,
+			*ruler,
+		); err != nil {
+			return err
+		}
+		setInputHash(&ss.Spec.Template, inputHash)
+		return controllerutil.SetControllerReference(ruler, ss, r.Scheme)
+	})
+
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update Status
+	ssNN := req.NamespacedName
+	ssNN.Name = ss.Name
+	if err := r.Get(ctx, ssNN, ss); err != nil {
+		log.Error(err, "unable to fetch StatusfulSet", "namespaceName", ssNN)
+		return ctrl.Result{}, err
+	}
+	ruler.Status.StatefulSetStatus = ss.Status
+
+	serviceNN := req.NamespacedName
+	serviceNN.Name = service.Name
+	if err := r.Get(ctx, serviceNN, service); err != nil {
+		log.Error(err, "unable to fetch Service", "namespaceName", serviceNN)
+		return ctrl.Result{}, err
+	}
+	ruler.Status.ServiceStatus = service.Status
+
+	ruler.Status.RuleConfigMaps = nil
+	for _, cm := range sources {
+		ruler.Status.RuleConfigMaps = append(ruler.Status.RuleConfigMaps, cm.Name)
+	}
+
+	ruler.Status.ObservedGeneration = ruler.Generation
+	ruler.Status.Conditions = setWorkloadConditions(
+		ruler.Status.Conditions,
+		ruler.Generation,
+		ss.Status.ObservedGeneration >= ss.Generation,
+		*ss.Spec.Replicas,
+		ss.Status.UpdatedReplicas,
+		ss.Status.ReadyReplicas,
+	)
+	ruler.Status.Conditions = setReconcileErrorCondition(ruler.Status.Conditions, ruler.Generation, nil)
+
+	err = r.Status().Update(ctx, ruler)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// selectRuleConfigMaps returns the ConfigMaps matching the ruler's
+// ruleSelector, sorted by name. A nil selector selects nothing.
+func (r *RulerReconciler) selectRuleConfigMaps(ctx context.Context, ruler *thanosv1beta1.Ruler) ([]corev1.ConfigMap, error) {
+	if ruler.Spec.RuleSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ruler.Spec.RuleSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	cms := &corev1.ConfigMapList{}
+	err = r.List(ctx, cms, client.UseListOptions(&client.ListOptions{
+		Namespace:     ruler.Namespace,
+		LabelSelector: selector,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []corev1.ConfigMap
+	for _, cm := range cms.Items {
+		// never feed the generated ConfigMap back into itself
+		if cm.Name == ruler.Name+"-rules" {
+			continue
+		}
+		selected = append(selected, cm)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Name < selected[j].Name
+	})
+	return selected, nil
+}
+
+// rulersForConfigMap maps a ConfigMap event to every Ruler in the same
+// namespace whose ruleSelector matches it.
+func (r *RulerReconciler) rulersForConfigMap(obj handler.MapObject) []reconcile.Request {
+	rulers := &thanosv1beta1.RulerList{}
+	if err := r.List(context.Background(), rulers, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list rulers", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ruler := range rulers.Items {
+		if ruler.Spec.RuleSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(ruler.Spec.RuleSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(obj.Meta.GetLabels())) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      ruler.Name,
+					Namespace: ruler.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+// rulersForSecret maps a Secret event to every Ruler in the same namespace
+// whose pods read it.
+func (r *RulerReconciler) rulersForSecret(obj handler.MapObject) []reconcile.Request {
+	rulers := &thanosv1beta1.RulerList{}
+	if err := r.List(context.Background(), rulers, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list rulers", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, ruler := range rulers.Items {
+		if containsString(rulerSecrets(ruler), obj.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      ruler.Name,
+					Namespace: ruler.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *RulerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&thanosv1beta1.Ruler{}).
+		Owns(&appsv1.StatefulSet{}). // Generates StatefulSets
+		Owns(&corev1.Service{}).     // Generates Services
+		Owns(&corev1.Secret{}).      // Generates object storage Secrets
+		Owns(&corev1.ConfigMap{}).   // Generates rule ConfigMaps
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.rulersForConfigMap),
+		}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.rulersForSecret),
+		}).
+		Complete(r)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// SidecarReconciler reconciles a Sidecar object. The sidecar containers
+// themselves are injected by SidecarInjector, the reconciler only manages
+// the Service exposing their StoreAPI.
+type SidecarReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=sidecars,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=thanos.orangesys.io,resources=sidecars/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+func (r *SidecarReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("sidecar", req.NamespacedName)
+
+	// Fetch the sidecar instance
+	sidecar := &thanosv1beta1.Sidecar{}
+	if err := r.Get(ctx, req.NamespacedName, sidecar); err != nil {
+		if ignoreNotFound(err) == nil {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch thanos sidecar")
+		return ctrl.Result{}, err
+	}
+
+	// Record failed reconciliations in the status
+	defer func() {
+		if err == nil {
+			return
+		}
+		sidecar.Status.Conditions = setReconcileErrorCondition(sidecar.Status.Conditions, sidecar.Generation, err)
+		if err := r.Status().Update(ctx, sidecar); err != nil {
+			log.Error(err, "unable to record reconcile error")
+		}
+	}()
+
+	// Generate object storage Secret
+	if err := reconcileObjstoreSecret(ctx, r.Client, r.Scheme, sidecar, sidecar.Spec.ObjectStorageConfig, sidecar.Spec.ObjectStorage); err != nil {
+		log.Error(err, "unable to generate object storage Secret")
+		return ctrl.Result{}, err
+	}
+
+	// Generate Service
+	service := &corev1.Service{
+		ObjectMeta: ctrl.ObjectMeta{
+			Name:      req.Name,
+			Namespace: req.Namespace,
+		},
+	}
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
+		makeSidecarService(service, *sidecar)
+		return controllerutil.SetControllerReference(sidecar, service, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update Status
+	serviceNN := req.NamespacedName
+	serviceNN.Name = service.Name
+	if err := r.Get(ctx, serviceNN, service); err != nil {
+		log.Error(err, "unable to fetch Service", "namespaceName", serviceNN)
+		return ctrl.Result{}, err
+	}
+	sidecar.Status.ServiceStatus = service.Status
+
+	desired, injected, ready, err := r.countSidecars(ctx, sidecar)
+	if err != nil {
+		log.Error(err, "unable to list selected pods")
+		return ctrl.Result{}, err
+	}
+	sidecar.Status.ObservedGeneration = sidecar.Generation
+	sidecar.Status.Conditions = setWorkloadConditions(
+		sidecar.Status.Conditions,
+		sidecar.Generation,
+		true,
+		desired,
+		injected,
+		ready,
+	)
+	sidecar.Status.Conditions = setReconcileErrorCondition(sidecar.Status.Conditions, sidecar.Generation, nil)
+
+	err = r.Status().Update(ctx, sidecar)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// countSidecars counts the pods selected by sidecar, those running an
+// injected sidecar and those whose sidecar is ready.
+func (r *SidecarReconciler) countSidecars(ctx context.Context, sidecar *thanosv1beta1.Sidecar) (desired, injected, ready int32, err error) {
+	if sidecar.Spec.Selector == nil {
+		return 0, 0, 0, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(sidecar.Spec.Selector)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	pods := &corev1.PodList{}
+	err = r.List(ctx, pods, client.UseListOptions(&client.ListOptions{
+		Namespace:     sidecar.Namespace,
+		LabelSelector: selector,
+	}))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, pod := range pods.Items {
+		desired++
+		for _, c := range pod.Spec.Containers {
+			if c.Name == sidecarName {
+				injected++
+			}
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name == sidecarName && cs.Ready {
+				ready++
+			}
+		}
+	}
+	return desired, injected, ready, nil
+}
+
+// sidecarsForPod maps a pod event to every Sidecar in the same namespace
+// whose selector matches it.
+func (r *SidecarReconciler) sidecarsForPod(obj handler.MapObject) []reconcile.Request {
+	sidecars := &thanosv1beta1.SidecarList{}
+	if err := r.List(context.Background(), sidecars, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list sidecars", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, sidecar := range sidecars.Items {
+		if sidecar.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(sidecar.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(obj.Meta.GetLabels())) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      sidecar.Name,
+					Namespace: sidecar.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *SidecarReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&thanosv1beta1.Sidecar{}).
+		Owns(&corev1.Service{}). // Generates Services
+		Owns(&corev1.Secret{}).  // Generates object storage Secrets
+		Watches(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.sidecarsForPod),
+		}).
+		Complete(r)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// sidecarInjectionLabel opts pods into sidecar injection. The pod webhook is
+// only called for pods carrying it.
+const sidecarInjectionLabel = "thanos.orangesys.io/inject"
+
+// SidecarInjector injects the thanos sidecar into Prometheus pods labeled
+// for injection and selected by a Sidecar in the pod's namespace
+type SidecarInjector struct {
+	Client client.Client
+	Log    logr.Logger
+	// DefaultResources are the requests given to sidecars which leave them out
+	DefaultResources corev1.ResourceList
+	decoder          *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=ignore,groups="",resources=pods,verbs=create,versions=v1,name=sidecar.thanos.orangesys.io
+
+// Handle injects the sidecar of the first Sidecar whose selector matches the pod
+func (i *SidecarInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := i.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if pod.Labels[sidecarInjectionLabel] != "true" {
+		return admission.Allowed("pod not labeled for sidecar injection")
+	}
+
+	sidecars := &thanosv1beta1.SidecarList{}
+	if err := i.Client.List(ctx, sidecars, client.InNamespace(req.Namespace)); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	for _, sidecar := range sidecars.Items {
+		if sidecar.Spec.Selector == nil {
+			i.Log.Info("skipping sidecar without selector", "sidecar", sidecar.Name)
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(sidecar.Spec.Selector)
+		if err != nil {
+			i.Log.Error(err, "invalid sidecar selector", "sidecar", sidecar.Name)
+			continue
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		if err := injectSidecar(pod, i.DefaultResources, sidecar); err != nil {
+			// Admit the pod rather than block Prometheus on a broken Sidecar
+			i.Log.Error(err, "unable to inject sidecar", "sidecar", sidecar.Name)
+			return admission.Allowed("sidecar " + sidecar.Name + " is invalid: " + err.Error())
+		}
+		marshaled, err := json.Marshal(pod)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+	}
+
+	return admission.Allowed("no sidecar selects this pod")
+}
+
+// InjectDecoder injects the decoder into a SidecarInjector.
+func (i *SidecarInjector) InjectDecoder(d *admission.Decoder) error {
+	i.decoder = d
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+var _ = Describe("Sidecar injection", func() {
+	var injector *SidecarInjector
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(thanosv1beta1.AddToScheme(scheme)).To(Succeed())
+		image := "quay.io/thanos/thanos:v0.12.2"
+		sidecar := &thanosv1beta1.Sidecar{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "prometheus",
+				Namespace: "monitoring",
+			},
+			Spec: thanosv1beta1.SidecarSpec{
+				Image: &image,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "prometheus"},
+				},
+				ObjectStorage: &thanosv1beta1.ObjectStorage{
+					GCS: &thanosv1beta1.GCSObjectStorage{Bucket: "metrics"},
+				},
+			},
+		}
+		injector = &SidecarInjector{Client: fake.NewFakeClientWithScheme(scheme, sidecar)}
+		Expect(injector.InjectDecoder(newTestDecoder())).To(Succeed())
+
+		pod = &corev1.Pod{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "prometheus-0",
+				Namespace: "monitoring",
+				Labels:    map[string]string{"app": "prometheus"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "prometheus"}},
+			},
+		}
+	})
+
+	request := func() admission.Request {
+		req := admissionRequest(admissionv1beta1.Create, pod, nil)
+		req.Namespace = pod.Namespace
+		return req
+	}
+
+	It("should leave pods not labeled for injection alone", func() {
+		resp := injector.Handle(context.Background(), request())
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	It("should inject the sidecar into labeled pods", func() {
+		pod.Labels[sidecarInjectionLabel] = "true"
+
+		resp := injector.Handle(context.Background(), request())
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(patchedPaths(resp)).To(HaveKey("/spec/containers/1"))
+	})
+})
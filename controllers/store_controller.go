@@ -23,12 +23,18 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
 )
@@ -39,14 +45,19 @@ type StoreReconciler struct {
 	Log      logr.Logger
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
+	// DefaultResources are the requests given to containers which leave them out
+	DefaultResources corev1.ResourceList
 }
 
 // +kubebuilder:rbac:groups=thanos.orangesys.io,resources=stores,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=thanos.orangesys.io,resources=stores/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=apps,resources=deployment,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 
-func (r *StoreReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *StoreReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	ctx := context.Background()
 	log := r.Log.WithValues("store", req.NamespacedName)
 
@@ -59,48 +70,397 @@ func (r *StoreReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	// Generate Service
+	// Record failed reconciliations in the status
+	defer func() {
+		if err == nil {
+			return
+		}
+		store.Status.Conditions = setReconcileErrorCondition(store.Status.Conditions, store.Generation, err)
+		if err := r.Status().Update(ctx, store); err != nil {
+			log.Error(err, "unable to record reconcile error")
+		}
+	}()
+
+	// Generate object storage Secret
+	if err := reconcileObjstoreSecret(ctx, r.Client, r.Scheme, store, store.Spec.ObjectStorageConfig, store.Spec.ObjectStorage); err != nil {
+		log.Error(err, "unable to generate object storage Secret")
+		return ctrl.Result{}, err
+	}
+
+	// Generate the Service selecting every shard
 	service := &corev1.Service{
 		ObjectMeta: ctrl.ObjectMeta{
 			Name:      req.Name,
 			Namespace: req.Namespace,
 		},
 	}
-	_, err := ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
-		makeService(service, service.Name)
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, service, func() error {
+		makeService(service, componentStore, store.Name, store.Spec.ServiceMetadata)
 		return controllerutil.SetControllerReference(store, service, r.Scheme)
 	})
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	// Generate Deployment
-	dm := &appsv1.Deployment{
+
+	// Generate the headless Service listing the pods of every shard, through
+	// which queriers discover each of them
+	headless := &corev1.Service{
 		ObjectMeta: ctrl.ObjectMeta{
-			Name:      req.Name,
+			Name:      headlessServiceName(req.Name),
 			Namespace: req.Namespace,
 		},
 	}
-
-	_, err = ctrl.CreateOrUpdate(ctx, r.Client, dm, func() error {
-		setStoreDeployment(
-			dm,
-			service,
-			*store,
-		)
-		return controllerutil.SetControllerReference(store, dm, r.Scheme)
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, headless, func() error {
+		if err := refuseAdoption(headless, store); err != nil {
+			return err
+		}
+		makeHeadlessService(headless, componentStore, store.Name)
+		return controllerutil.SetControllerReference(store, headless, r.Scheme)
 	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
+	// Hash the inputs of the pods, rolling them when one changes
+	inputHash, err := hashInputs(ctx, r.Client, req.Namespace, storeSecrets(*store))
 	if err != nil {
+		log.Error(err, "unable to hash pod inputs")
+		return ctrl.Result{}, err
+	}
+
+	// Generate a workload per shard, and a Service when sharded. Stores
+	// with persistence run as StatefulSets keeping their data dir.
+	shards := storeShards(*store)
+	sharded := store.Spec.Shards != nil
+	persistent := storePersistent(*store)
+	var deployments []*appsv1.Deployment
+	var statefulSets []*appsv1.StatefulSet
+	for _, shard := range shards {
+		shard := shard
+		if sharded {
+			shardService := &corev1.Service{
+				ObjectMeta: ctrl.ObjectMeta{
+					Name:      shard.Name,
+					Namespace: req.Namespace,
+				},
+			}
+			_, err = ctrl.CreateOrUpdate(ctx, r.Client, shardService, func() error {
+				makeStoreShardService(shardService, shard, *store)
+				return controllerutil.SetControllerReference(store, shardService, r.Scheme)
+			})
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		if persistent {
+			// The headless Service of the shard governs its StatefulSet,
+			// an unsharded store is governed by the one created above
+			governingName := headlessServiceName(shard.Name)
+			if sharded {
+				governing := &corev1.Service{
+					ObjectMeta: ctrl.ObjectMeta{
+						Name:      governingName,
+						Namespace: req.Namespace,
+					},
+				}
+				_, err = ctrl.CreateOrUpdate(ctx, r.Client, governing, func() error {
+					if err := refuseAdoption(governing, store); err != nil {
+						return err
+					}
+					makeStoreShardHeadlessService(governing, shard, *store)
+					return controllerutil.SetControllerReference(store, governing, r.Scheme)
+				})
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+
+			// The governing Service of a StatefulSet is immutable.
+			// StatefulSets created by earlier versions are governed by the
+			// Service of the shard, which is not headless: they are
+			// deleted leaving their pods and volumes to the StatefulSet
+			// replacing them.
+			ss := &appsv1.StatefulSet{
+				ObjectMeta: ctrl.ObjectMeta{
+					Name:      shard.Name,
+					Namespace: req.Namespace,
+				},
+			}
+			ssNN := types.NamespacedName{Name: ss.Name, Namespace: ss.Namespace}
+			if err := r.Get(ctx, ssNN, ss); ignoreNotFound(err) != nil {
+				log.Error(err, "unable to fetch StatefulSet", "namespaceName", ssNN)
+				return ctrl.Result{}, err
+			}
+			if !ss.CreationTimestamp.IsZero() && ss.Spec.ServiceName != governingName {
+				if ss.DeletionTimestamp == nil {
+					log.Info("recreating StatefulSet to change its governing Service", "from", ss.Spec.ServiceName, "to", governingName)
+					r.Recorder.Eventf(store, corev1.EventTypeNormal, "RecreatingStatefulSet",
+						"Recreating StatefulSet %s governed by Service %s", ss.Name, ss.Spec.ServiceName)
+					err := r.Delete(ctx, ss, client.PropagationPolicy(metav1.DeletePropagationOrphan))
+					if ignoreNotFound(err) != nil {
+						return ctrl.Result{}, err
+					}
+				}
+				return ctrl.Result{Requeue: true}, nil
+			}
+			_, err = ctrl.CreateOrUpdate(ctx, r.Client, ss, func() error {
+				if err := setStoreStatefulSet(
+					ss,
+					r.DefaultResources,
+					shard,
+					*store,
+				); err != nil {
+					return err
+				}
+				setInputHash(&ss.Spec.Template, inputHash)
+				return controllerutil.SetControllerReference(store, ss, r.Scheme)
+			})
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			statefulSets = append(statefulSets, ss)
+			continue
+		}
+
+		dm := &appsv1.Deployment{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      shard.Name,
+				Namespace: req.Namespace,
+			},
+		}
+		_, err = ctrl.CreateOrUpdate(ctx, r.Client, dm, func() error {
+			if err := setStoreDeployment(
+				dm,
+				r.DefaultResources,
+				shard,
+				*store,
+			); err != nil {
+				return err
+			}
+			setInputHash(&dm.Spec.Template, inputHash)
+			return controllerutil.SetControllerReference(store, dm, r.Scheme)
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		deployments = append(deployments, dm)
+	}
+
+	// Remove the workloads and Services of shards no longer wanted
+	if err := r.deleteStaleShards(ctx, store, shards, persistent); err != nil {
+		log.Error(err, "unable to delete stale shards")
 		return ctrl.Result{}, err
 	}
 
+	// Expand the volumes of the StatefulSets to the requested storage
+	var pending, total int
+	var claim corev1.PersistentVolumeClaim
+	if persistent {
+		claim, err = makeStoreVolumeClaimTemplate(*store)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	for _, ss := range statefulSets {
+		ssPending, ssTotal, err := resizeVolumeClaims(ctx, r.Client, ss, claim)
+		if err != nil {
+			log.Error(err, "unable to resize PersistentVolumeClaims")
+			return ctrl.Result{}, err
+		}
+		pending += ssPending
+		total += ssTotal
+	}
+
 	// Update Status
+	var desired, updated int32
+	observed := true
+	store.Status.Replicas = 0
+	store.Status.UpdatedReplicas = 0
+	store.Status.AvailableReplicas = 0
+	store.Status.UnavailableReplicas = 0
+	for _, dm := range deployments {
+		dmNN := req.NamespacedName
+		dmNN.Name = dm.Name
+		if err := r.Get(ctx, dmNN, dm); err != nil {
+			log.Error(err, "unable to fetch Deployment", "namespaceName", dmNN)
+			return ctrl.Result{}, err
+		}
+		store.Status.UpdatedReplicas += dm.Status.UpdatedReplicas
+		store.Status.AvailableReplicas += dm.Status.AvailableReplicas
+		store.Status.UnavailableReplicas += dm.Status.UnavailableReplicas
+		desired += *dm.Spec.Replicas
+		updated += dm.Status.UpdatedReplicas
+		observed = observed && dm.Status.ObservedGeneration >= dm.Generation
+	}
+	for _, ss := range statefulSets {
+		ssNN := req.NamespacedName
+		ssNN.Name = ss.Name
+		if err := r.Get(ctx, ssNN, ss); err != nil {
+			log.Error(err, "unable to fetch StatefulSet", "namespaceName", ssNN)
+			return ctrl.Result{}, err
+		}
+		store.Status.UpdatedReplicas += ss.Status.UpdatedReplicas
+		store.Status.AvailableReplicas += ss.Status.ReadyReplicas
+		store.Status.UnavailableReplicas += ss.Status.Replicas - ss.Status.ReadyReplicas
+		desired += *ss.Spec.Replicas
+		updated += ss.Status.UpdatedReplicas
+		observed = observed && ss.Status.ObservedGeneration >= ss.Generation
+	}
+	// spec.replicas is the replica count of each shard: the scale
+	// subresource reports the pods of the first shard, so that the
+	// autoscaler scales every shard by the load of one.
+	store.Status.DeploymentStatus = appsv1.DeploymentStatus{}
+	store.Status.StatefulSetStatus = appsv1.StatefulSetStatus{}
+	store.Status.Shards = 0
+	if sharded {
+		store.Status.Shards = int32(len(shards))
+	}
+	if persistent {
+		store.Status.Replicas = statefulSets[0].Status.Replicas
+		store.Status.Selector = metav1.FormatLabelSelector(statefulSets[0].Spec.Selector)
+		if !sharded {
+			store.Status.StatefulSetStatus = statefulSets[0].Status
+		}
+	} else {
+		store.Status.Replicas = deployments[0].Status.Replicas
+		store.Status.Selector = metav1.FormatLabelSelector(deployments[0].Spec.Selector)
+		if !sharded {
+			store.Status.DeploymentStatus = deployments[0].Status
+		}
+	}
 
+	serviceNN := req.NamespacedName
+	serviceNN.Name = service.Name
+	if err := r.Get(ctx, serviceNN, service); err != nil {
+		log.Error(err, "unable to fetch Service", "namespaceName", serviceNN)
+		return ctrl.Result{}, err
+	}
+	store.Status.ServiceStatus = service.Status
+
+	store.Status.ObservedGeneration = store.Generation
+	store.Status.Conditions = setWorkloadConditions(
+		store.Status.Conditions,
+		store.Generation,
+		observed,
+		desired,
+		updated,
+		store.Status.AvailableReplicas,
+	)
+	if persistent {
+		storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		store.Status.Conditions = setStorageResizingCondition(
+			store.Status.Conditions,
+			store.Generation,
+			pending,
+			total,
+			storage.String(),
+		)
+	}
+	store.Status.Conditions = setReconcileErrorCondition(store.Status.Conditions, store.Generation, nil)
+
+	err = r.Status().Update(ctx, store)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if pending > 0 {
+		return ctrl.Result{RequeueAfter: volumeResizeRequeueAfter}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
+// deleteStaleShards deletes the workloads and Services owned by store which
+// belong to none of shards, such as those left over when the shard count
+// shrinks, sharding is turned on or off, or persistence is enabled or disabled.
+func (r *StoreReconciler) deleteStaleShards(
+	ctx context.Context,
+	store *thanosv1beta1.Store,
+	shards []storeShard,
+	persistent bool,
+) error {
+	workloads := map[string]bool{}
+	// The Services named after the store select every shard
+	services := map[string]bool{
+		store.Name:                      true,
+		headlessServiceName(store.Name): true,
+	}
+	for _, shard := range shards {
+		workloads[shard.Name] = true
+		services[shard.Name] = true
+		if persistent {
+			services[headlessServiceName(shard.Name)] = true
+		}
+	}
+	deployments, statefulSets := workloads, map[string]bool{}
+	if persistent {
+		deployments, statefulSets = statefulSets, workloads
+	}
+
+	owned := []struct {
+		list   runtime.Object
+		wanted map[string]bool
+	}{
+		{&appsv1.DeploymentList{}, deployments},
+		{&appsv1.StatefulSetList{}, statefulSets},
+		{&corev1.ServiceList{}, services},
+	}
+	for _, o := range owned {
+		if err := r.List(ctx, o.list, client.InNamespace(store.Namespace)); err != nil {
+			return err
+		}
+		items, err := apimeta.ExtractList(o.list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			obj, err := apimeta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			if o.wanted[obj.GetName()] || !metav1.IsControlledBy(obj, store) {
+				continue
+			}
+			if err := ignoreNotFound(r.Delete(ctx, item)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// storesForSecret maps a Secret event to every Store in the same namespace
+// whose pods read it.
+func (r *StoreReconciler) storesForSecret(obj handler.MapObject) []reconcile.Request {
+	stores := &thanosv1beta1.StoreList{}
+	if err := r.List(context.Background(), stores, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list stores", "namespace", obj.Meta.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, store := range stores.Items {
+		if containsString(storeSecrets(store), obj.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      store.Name,
+					Namespace: store.Namespace,
+				},
+			})
+		}
+	}
+	return requests
+}
+
 func (r *StoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&thanosv1beta1.Store{}).
+		Owns(&appsv1.Deployment{}).  // Generates Deployments
+		Owns(&appsv1.StatefulSet{}). // Generates StatefulSets
+		Owns(&corev1.Service{}).     // Generates Services
+		Owns(&corev1.Secret{}).      // Generates object storage Secrets
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.storesForSecret),
+		}).
 		Complete(r)
 }
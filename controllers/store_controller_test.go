@@ -0,0 +1,134 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+var _ = Describe("Store reconciler", func() {
+	It("should report the scale of one shard", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(thanosv1beta1.AddToScheme(scheme)).To(Succeed())
+		replicas, hashmodShards := int32(2), int32(3)
+		image := "quay.io/thanos/thanos:v0.12.2"
+		store := &thanosv1beta1.Store{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "store",
+				Namespace: "monitoring",
+			},
+			Spec: thanosv1beta1.StoreSpec{
+				Image:    &image,
+				Replicas: &replicas,
+				ObjectStorage: &thanosv1beta1.ObjectStorage{
+					GCS: &thanosv1beta1.GCSObjectStorage{Bucket: "metrics"},
+				},
+				Shards: &thanosv1beta1.StoreShards{HashmodShards: &hashmodShards},
+			},
+		}
+		c := fake.NewFakeClientWithScheme(scheme, store)
+		r := &StoreReconciler{
+			Client:   c,
+			Log:      ctrl.Log.WithName("store"),
+			Recorder: record.NewFakeRecorder(10),
+			Scheme:   scheme,
+		}
+		nn := types.NamespacedName{Name: "store", Namespace: "monitoring"}
+
+		// the shards are running their replicas
+		_, err := r.Reconcile(ctrl.Request{NamespacedName: nn})
+		Expect(err).NotTo(HaveOccurred())
+		for _, shard := range storeShards(*store) {
+			dm := &appsv1.Deployment{}
+			Expect(c.Get(context.Background(), types.NamespacedName{Name: shard.Name, Namespace: nn.Namespace}, dm)).To(Succeed())
+			Expect(*dm.Spec.Replicas).To(Equal(replicas))
+			dm.Status.Replicas = replicas
+			Expect(c.Status().Update(context.Background(), dm)).To(Succeed())
+		}
+		_, err = r.Reconcile(ctrl.Request{NamespacedName: nn})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Get(context.Background(), nn, store)).To(Succeed())
+		Expect(store.Status.Shards).To(Equal(hashmodShards))
+		Expect(store.Status.Replicas).To(Equal(replicas))
+		selector, err := metav1.ParseToLabelSelector(store.Status.Selector)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.MatchLabels).To(HaveKeyWithValue(storeShardLabel, "0"))
+	})
+
+	It("should recreate StatefulSets not governed by the headless Service", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(thanosv1beta1.AddToScheme(scheme)).To(Succeed())
+		image := "quay.io/thanos/thanos:v0.12.2"
+		store := &thanosv1beta1.Store{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "store",
+				Namespace: "monitoring",
+			},
+			Spec: thanosv1beta1.StoreSpec{
+				Image: &image,
+				ObjectStorage: &thanosv1beta1.ObjectStorage{
+					GCS: &thanosv1beta1.GCSObjectStorage{Bucket: "metrics"},
+				},
+				Persistence: &thanosv1beta1.StorePersistence{Enabled: true},
+			},
+		}
+		legacy := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "store",
+				Namespace:         "monitoring",
+				CreationTimestamp: metav1.Now(),
+			},
+			Spec: appsv1.StatefulSetSpec{ServiceName: "store"},
+		}
+		c := fake.NewFakeClientWithScheme(scheme, store, legacy)
+		r := &StoreReconciler{
+			Client:   c,
+			Log:      ctrl.Log.WithName("store"),
+			Recorder: record.NewFakeRecorder(10),
+			Scheme:   scheme,
+		}
+		nn := types.NamespacedName{Name: "store", Namespace: "monitoring"}
+
+		result, err := r.Reconcile(ctrl.Request{NamespacedName: nn})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Requeue).To(BeTrue())
+		Expect(c.Get(context.Background(), nn, &appsv1.StatefulSet{})).NotTo(Succeed())
+
+		_, err = r.Reconcile(ctrl.Request{NamespacedName: nn})
+		Expect(err).NotTo(HaveOccurred())
+		ss := &appsv1.StatefulSet{}
+		Expect(c.Get(context.Background(), nn, ss)).To(Succeed())
+		Expect(ss.Spec.ServiceName).To(Equal(headlessServiceName("store")))
+	})
+})
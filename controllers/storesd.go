@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+const (
+	storeSDFile = "stores.json"
+	storeSDDir  = "/etc/thanos/store-sd/"
+)
+
+// targetGroup mirrors a file SD target group read by thanos query
+type targetGroup struct {
+	Targets []string `json:"targets"`
+}
+
+// storeSDConfigMapName returns the name of the generated file SD ConfigMap
+func storeSDConfigMapName(t thanosv1beta1.Querier) string {
+	return t.Name + "-store-sd"
+}
+
+// storeEndpoint returns the address resolving to the gRPC StoreAPI of every
+// pod listed by an in-cluster headless Service
+func storeEndpoint(service, namespace string) string {
+	return fmt.Sprintf("dnssrv+_grpc._tcp.%s.%s.svc", service, namespace)
+}
+
+// makeStoreSDConfigMap renders the static stores of t, followed by the
+// endpoints of the pods of the discovered store Services, into cm
+func makeStoreSDConfigMap(cm *corev1.ConfigMap, t thanosv1beta1.Querier, stores []string) error {
+	targets := []string{}
+	targets = append(targets, t.Spec.Stores...)
+	for _, store := range stores {
+		targets = append(targets, storeEndpoint(store, t.Namespace))
+	}
+
+	data, err := json.MarshalIndent([]targetGroup{{Targets: targets}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	cm.Labels = map[string]string{
+		"thanos": t.Name,
+	}
+	cm.Data = map[string]string{
+		storeSDFile: string(data),
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+var _ = Describe("Store discovery", func() {
+	var querier thanosv1beta1.Querier
+	stores := []string{
+		headlessServiceName("ingest"),
+		headlessServiceName("store"),
+	}
+
+	BeforeEach(func() {
+		querier = thanosv1beta1.Querier{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "query",
+				Namespace: "monitoring",
+			},
+			Spec: thanosv1beta1.QuerierSpec{
+				Stores: []string{"prometheus.example.com:10901"},
+			},
+		}
+	})
+
+	It("should pass every pod of the discovered stores as --store", func() {
+		dm := &appsv1.Deployment{}
+
+		Expect(setQuerierDeployment(dm, &corev1.Service{}, nil, stores, querier)).To(Succeed())
+
+		args := dm.Spec.Template.Spec.Containers[0].Args
+		Expect(args).To(ContainElement("--store=prometheus.example.com:10901"))
+		Expect(args).To(ContainElement("--store=dnssrv+_grpc._tcp.ingest-headless.monitoring.svc"))
+		Expect(args).To(ContainElement("--store=dnssrv+_grpc._tcp.store-headless.monitoring.svc"))
+	})
+
+	It("should list every pod of the discovered stores as file SD targets", func() {
+		querier.Spec.FileSD = true
+		cm := &corev1.ConfigMap{}
+
+		Expect(makeStoreSDConfigMap(cm, querier, stores)).To(Succeed())
+
+		var groups []targetGroup
+		Expect(json.Unmarshal([]byte(cm.Data[storeSDFile]), &groups)).To(Succeed())
+		Expect(groups).To(Equal([]targetGroup{{
+			Targets: []string{
+				"prometheus.example.com:10901",
+				"dnssrv+_grpc._tcp.ingest-headless.monitoring.svc",
+				"dnssrv+_grpc._tcp.store-headless.monitoring.svc",
+			},
+		}}))
+
+		dm := &appsv1.Deployment{}
+		Expect(setQuerierDeployment(dm, &corev1.Service{}, nil, stores, querier)).To(Succeed())
+		for _, arg := range dm.Spec.Template.Spec.Containers[0].Args {
+			Expect(arg).NotTo(HavePrefix("--store="))
+		}
+	})
+})
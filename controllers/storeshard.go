@@ -0,0 +1,184 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// storeShardLabel tells the pods of the shards of a store apart
+const storeShardLabel = "thanos.orangesys.io/shard"
+
+var (
+	// timeRangeVersion is the first thanos release accepting --min-time
+	// and --max-time on store
+	timeRangeVersion = version.MustParseSemantic("v0.7.0")
+	// hashmodVersion is the first thanos release able to select the blocks
+	// of a store by the hash of their __block_id
+	hashmodVersion = version.MustParseSemantic("v0.12.0")
+)
+
+// storeShard is the part of the bucket served by one store workload
+type storeShard struct {
+	// Name of the workload and Service of the shard
+	Name string
+	// Labels select the pods of the shard, nil when the store is not sharded
+	Labels map[string]string
+	// MinTime and MaxTime bound the time range served
+	MinTime string
+	MaxTime string
+	// Modulus is the number of hashmod shards, Remainder the one served
+	Modulus   int32
+	Remainder int32
+}
+
+// relabelConfig mirrors the Prometheus relabel configuration read by thanos
+type relabelConfig struct {
+	Action       string   `json:"action"`
+	SourceLabels []string `json:"source_labels,omitempty"`
+	TargetLabel  string   `json:"target_label,omitempty"`
+	Modulus      uint64   `json:"modulus,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+}
+
+// storeShards returns the shards of t. A store without shards runs as a
+// single shard named after it, as it did before sharding existed.
+func storeShards(t thanosv1beta1.Store) []storeShard {
+	if t.Spec.Shards == nil {
+		return []storeShard{
+			{
+				Name:    t.Name,
+				MinTime: t.Spec.MinTime,
+				MaxTime: t.Spec.MaxTime,
+				Modulus: 1,
+			},
+		}
+	}
+
+	partitions := t.Spec.Shards.TimePartitions
+	if len(partitions) == 0 {
+		partitions = []thanosv1beta1.StoreTimePartition{
+			{
+				MinTime: t.Spec.MinTime,
+				MaxTime: t.Spec.MaxTime,
+			},
+		}
+	}
+	modulus := int32(1)
+	if t.Spec.Shards.HashmodShards != nil && *t.Spec.Shards.HashmodShards > 1 {
+		modulus = *t.Spec.Shards.HashmodShards
+	}
+
+	var shards []storeShard
+	for _, partition := range partitions {
+		for remainder := int32(0); remainder < modulus; remainder++ {
+			index := strconv.Itoa(len(shards))
+			shards = append(shards, storeShard{
+				Name:      t.Name + "-shard-" + index,
+				Labels:    map[string]string{storeShardLabel: index},
+				MinTime:   partition.MinTime,
+				MaxTime:   partition.MaxTime,
+				Modulus:   modulus,
+				Remainder: remainder,
+			})
+		}
+	}
+	return shards
+}
+
+// storeShardsVersion returns the first thanos release supporting the time
+// range and shards of t, nil when any release does.
+func storeShardsVersion(t thanosv1beta1.Store) *version.Version {
+	for _, shard := range storeShards(t) {
+		if shard.Modulus > 1 {
+			return hashmodVersion
+		}
+	}
+	for _, shard := range storeShards(t) {
+		if shard.MinTime != "" || shard.MaxTime != "" {
+			return timeRangeVersion
+		}
+	}
+	return nil
+}
+
+// makeShardArgs returns the flags restricting a store running image to the
+// blocks of shard. Releases not supporting them are refused rather than
+// left to fail on unknown flags.
+func makeShardArgs(image string, shard storeShard) ([]string, error) {
+	var args []string
+	if shard.MinTime != "" || shard.MaxTime != "" {
+		if !imageAtLeast(image, timeRangeVersion) {
+			return nil, fmt.Errorf("time ranges require thanos v%s or later, got %s", timeRangeVersion, image)
+		}
+	}
+	if shard.MinTime != "" {
+		args = append(args, fmt.Sprintf("--min-time=%s", shard.MinTime))
+	}
+	if shard.MaxTime != "" {
+		args = append(args, fmt.Sprintf("--max-time=%s", shard.MaxTime))
+	}
+	if shard.Modulus > 1 {
+		if !imageAtLeast(image, hashmodVersion) {
+			return nil, fmt.Errorf("hashmod shards require thanos v%s or later, got %s", hashmodVersion, image)
+		}
+		config, err := yaml.Marshal([]relabelConfig{
+			{
+				Action:       "hashmod",
+				SourceLabels: []string{"__block_id"},
+				TargetLabel:  "shard",
+				Modulus:      uint64(shard.Modulus),
+			},
+			{
+				Action:       "keep",
+				SourceLabels: []string{"shard"},
+				Regex:        strconv.Itoa(int(shard.Remainder)),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, fmt.Sprintf("--selector.relabel-config=%s", config))
+	}
+	return args, nil
+}
+
+// makeStoreShardService set fields on the Service exposing the pods of shard
+func makeStoreShardService(service *corev1.Service, shard storeShard, t thanosv1beta1.Store) {
+	makeService(service, componentStore, t.Name, t.Spec.ServiceMetadata)
+	for k, v := range shard.Labels {
+		service.Labels[k] = v
+		service.Spec.Selector[k] = v
+	}
+}
+
+// makeStoreShardHeadlessService set fields on the headless Service governing
+// the StatefulSet of shard
+func makeStoreShardHeadlessService(service *corev1.Service, shard storeShard, t thanosv1beta1.Store) {
+	makeHeadlessService(service, componentStore, t.Name)
+	for k, v := range shard.Labels {
+		service.Labels[k] = v
+		service.Spec.Selector[k] = v
+	}
+}
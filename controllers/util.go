@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"path"
+	"sort"
+	"strconv"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -10,19 +14,36 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
 )
 
 const (
-	governingServiceName = "thanos"
-	defaultThanosVersion = "v0.5.0"
-	defaultRetetion      = "24h"
-	receiveStorage       = "2Gi"
-	receiverDir          = "/thanos-receive"
-	secretsDir           = "/etc/thanos/secrets/"
-	sSetInputHashName    = "prometheus-operator-input-hash"
+	componentQuerier   = "querier"
+	componentStore     = "store"
+	componentReceiver  = "receiver"
+	componentRuler     = "ruler"
+	componentCompactor = "compactor"
+)
+
+const (
+	legacyGoverningServiceName = "thanos"
+	defaultThanosVersion       = "v0.12.2"
+	defaultRetetion            = "24h"
+	receiveStorage             = "2Gi"
+	receiverDir                = "/thanos-receive"
+	compactorStorage           = "10Gi"
+	compactorDir               = "/thanos-compact"
+	rulerStorage               = "2Gi"
+	rulerDir                   = "/thanos-rule"
+	rulesDir                   = "/etc/thanos/rules/"
+	secretsDir                 = "/etc/thanos/secrets/"
+	userSecretsDir             = "/etc/thanos/user-secrets/"
+	sidecarName                = "thanos-sidecar"
+	prometheusURL              = "http://localhost:9090"
+	prometheusTSDBPath         = "/prometheus"
 )
 
 var (
@@ -33,75 +54,137 @@ var (
 	managedByOperatorLabels           = map[string]string{
 		managedByOperatorLabel: managedByOperatorLabelValue,
 	}
-
-	probeTimeoutSeconds int32 = 3
 )
 
 // setStoreDeployment set fields on appsv1.Depployment pointer generated
+// shard is the part of the bucket served by the Deployment.
 func setStoreDeployment(
 	dm *appsv1.Deployment,
-	service *corev1.Service,
+	defaults corev1.ResourceList,
+	shard storeShard,
 	t thanosv1beta1.Store,
-) {
+) error {
 	t = *t.DeepCopy()
+	defaultStore(&t)
+
+	t.Spec.Resources = defaultResources(t.Spec.Resources, defaults)
 
-	podLabels := map[string]string{
-		"app":    "store",
-		"thanos": t.Name,
+	dm.Spec.Selector = makeStoreSelector(dm.Spec.Selector, shard, t)
+	dm.Spec.Replicas = &miniReplicas
+	if t.Spec.Replicas != nil {
+		dm.Spec.Replicas = t.Spec.Replicas
 	}
-	if t.Spec.Resources.Requests == nil {
-		t.Spec.Resources.Requests = corev1.ResourceList{}
+
+	template, err := makeStorePodTemplate(dm.Spec.Selector, shard, nil, t)
+	if err != nil {
+		return err
 	}
+	dm.Spec.Template = *template
+	return nil
+}
 
-	_, memoryRequestFound := t.Spec.Resources.Requests[corev1.ResourceMemory]
-	memoryLimit, memoryLimitFound := t.Spec.Resources.Limits[corev1.ResourceMemory]
-	if !memoryRequestFound {
-		defaultMemoryRequest := resource.MustParse("1Gi")
-		compareResult := memoryLimit.Cmp(defaultMemoryRequest)
-		if memoryLimitFound && compareResult <= 0 {
-			t.Spec.Resources.Requests[corev1.ResourceMemory] = memoryLimit
-		} else {
-			t.Spec.Resources.Requests[corev1.ResourceMemory] = defaultMemoryRequest
-		}
+// setStoreStatefulSet set fields on a appsv1.StatefulSet pointer generated
+// shard is the part of the bucket served by the StatefulSet, whose pods
+// keep DataDir on a persistent volume.
+func setStoreStatefulSet(
+	ss *appsv1.StatefulSet,
+	defaults corev1.ResourceList,
+	shard storeShard,
+	t thanosv1beta1.Store,
+) error {
+	t = *t.DeepCopy()
+	defaultStore(&t)
+
+	claim, err := makeStoreVolumeClaimTemplate(t)
+	if err != nil {
+		return err
 	}
+	setVolumeClaimTemplate(ss, claim)
 
-	podAnnotations := map[string]string{}
+	t.Spec.Resources = defaultResources(t.Spec.Resources, defaults)
 
-	if t.Spec.PodMetadata != nil {
-		if t.Spec.PodMetadata.Labels != nil {
-			for k, v := range t.Spec.PodMetadata.Labels {
-				podLabels[k] = v
-			}
-		}
-		if t.Spec.PodMetadata.Annotations != nil {
-			for k, v := range t.Spec.PodMetadata.Annotations {
-				podAnnotations[k] = v
-			}
-		}
+	ss.Spec.Selector = makeStoreSelector(ss.Spec.Selector, shard, t)
+	// The headless Service of the shard governs its pods
+	ss.Spec.ServiceName = headlessServiceName(shard.Name)
+	ss.Spec.Replicas = &miniReplicas
+	if t.Spec.Replicas != nil {
+		ss.Spec.Replicas = t.Spec.Replicas
 	}
 
-	dm.Spec.Selector = &metav1.LabelSelector{
-		MatchLabels: podLabels,
+	volumemounts := []corev1.VolumeMount{
+		{
+			Name:      claim.Name,
+			MountPath: t.Spec.DataDir,
+		},
+	}
+	template, err := makeStorePodTemplate(ss.Spec.Selector, shard, volumemounts, t)
+	if err != nil {
+		return err
+	}
+	ss.Spec.Template = *template
+	return nil
+}
+
+// makeStoreSelector returns the selector of the workload running shard,
+// keeping the one it was created with.
+func makeStoreSelector(selector *metav1.LabelSelector, shard storeShard, t thanosv1beta1.Store) *metav1.LabelSelector {
+	if selector != nil {
+		return selector
+	}
+	selector = makeSelector(nil, componentStore, t.Name)
+	for k, v := range shard.Labels {
+		selector.MatchLabels[k] = v
+	}
+	return selector
+}
+
+// makeStoreVolumeClaimTemplate returns the claim template of the volumes of
+// t, which must have persistence enabled, sized by its storage.
+func makeStoreVolumeClaimTemplate(t thanosv1beta1.Store) (corev1.PersistentVolumeClaim, error) {
+	t = *t.DeepCopy()
+	defaultStore(&t)
+
+	claim, err := makeVolumeClaimTemplate(t.Spec.Persistence.Storage)
+	claim.Spec.StorageClassName = t.Spec.Persistence.StorageClassName
+	return claim, err
+}
+
+// makeStorePodTemplate returns the template of the pods matched by selector
+// serving shard. t must be defaulted, volumemounts are added to the store
+// container.
+func makeStorePodTemplate(
+	selector *metav1.LabelSelector,
+	shard storeShard,
+	volumemounts []corev1.VolumeMount,
+	t thanosv1beta1.Store,
+) (*corev1.PodTemplateSpec, error) {
+	shardArgs, err := makeShardArgs(*t.Spec.Image, shard)
+	if err != nil {
+		return nil, err
 	}
-	dm.Spec.Replicas = &miniReplicas
 
+	obs, err := makeObjstore(
+		objstoreConfig(t.Name, t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage),
+		t.Spec.ObjectStorageType,
+		t.Spec.BucketName,
+		t.Spec.SecretName,
+	)
+	if err != nil {
+		return nil, err
+	}
 	thanosArgs := []string{
 		"store",
 		fmt.Sprintf("--index-cache-size=%s", t.Spec.IndexCacheSize),
 		fmt.Sprintf("--chunk-pool-size=%s", t.Spec.ChunkPoolSize),
 		fmt.Sprintf("--data-dir=%s", t.Spec.DataDir),
-		fmt.Sprintf("--objstore.config=type: %s\nconfig:\n  bucket: \"%s\"", t.Spec.ObjectStorageType, t.Spec.BucketName),
 	}
+	thanosArgs = append(thanosArgs, obs.Args...)
+	thanosArgs = append(thanosArgs, shardArgs...)
 	if t.Spec.LogLevel != "" && t.Spec.LogLevel != "info" {
 		thanosArgs = append(thanosArgs, fmt.Sprintf("--log.level=%s", t.Spec.LogLevel))
 	}
 
-	env := []corev1.EnvVar{
-		{
-			Name:  "GOOGLE_APPLICATION_CREDENTIALS",
-			Value: secretsDir + t.Spec.SecretName + ".json",
-		},
-	}
+	env := obs.Env
 
 	ports := []corev1.ContainerPort{
 		{
@@ -115,100 +198,104 @@ func setStoreDeployment(
 	}
 
 	// mount to pod
-	volumemounts := []corev1.VolumeMount{
-		{
-			Name:      "google-cloud-key",
-			MountPath: secretsDir,
-		},
-	}
+	volumemounts = append(volumemounts, obs.VolumeMounts...)
 
+	liveness, readiness := makeProbes(*t.Spec.Image, t.Spec.Probes)
 	containers := []corev1.Container{
 		{
-			Name:         "store",
-			Image:        *t.Spec.Image,
-			Args:         thanosArgs,
-			Env:          env,
-			Ports:        ports,
-			VolumeMounts: volumemounts,
-		},
-	}
-	volumes := []corev1.Volume{
-		{
-			Name: "google-cloud-key",
-			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: t.Spec.SecretName,
-				},
-			},
+			Name:           "store",
+			Image:          *t.Spec.Image,
+			LivenessProbe:  liveness,
+			ReadinessProbe: readiness,
+			Args:           thanosArgs,
+			Env:            env,
+			Ports:          ports,
+			Resources:      t.Spec.Resources,
+			VolumeMounts:   volumemounts,
 		},
 	}
+	volumes := obs.Volumes
 
 	podspec := corev1.PodSpec{
 		TerminationGracePeriodSeconds: &gracePeriodTerm,
 		Containers:                    containers,
 		Volumes:                       volumes,
+		NodeSelector:                  t.Spec.NodeSelector,
+		Affinity:                      t.Spec.Affinity,
+		Tolerations:                   t.Spec.Tolerations,
+		PriorityClassName:             t.Spec.PriorityClassName,
+		SecurityContext:               t.Spec.SecurityContext,
 	}
 
-	dm.Spec.Template = corev1.PodTemplateSpec{
-		ObjectMeta: metav1.ObjectMeta{
-			Labels: dm.Spec.Selector.MatchLabels,
-		},
-		Spec: podspec,
+	if err := customizePodSpec(
+		&podspec,
+		t.Spec.Secrets,
+		t.Spec.Volumes,
+		t.Spec.VolumeMounts,
+		t.Spec.InitContainers,
+		t.Spec.Containers,
+	); err != nil {
+		return nil, err
 	}
+
+	return &corev1.PodTemplateSpec{
+		ObjectMeta: makePodTemplateMetadata(selector, t.Spec.PodMetadata, shard.Labels, nil),
+		Spec:       podspec,
+	}, nil
 }
 
 // setQuerierDeployment set fields on a appsv1.Depployment pointer generated
+// stores are the names of the StoreAPI Services the querier discovers.
 func setQuerierDeployment(
 	dm *appsv1.Deployment,
 	service *corev1.Service,
+	defaults corev1.ResourceList,
+	stores []string,
 	t thanosv1beta1.Querier,
-) {
+) error {
 	t = *t.DeepCopy()
+	defaultQuerier(&t)
 
-	podLabels := map[string]string{
-		"app":    "querier",
-		"thanos": t.Name,
-	}
-	if t.Spec.Resources.Requests == nil {
-		t.Spec.Resources.Requests = corev1.ResourceList{}
-	}
-
-	_, memoryRequestFound := t.Spec.Resources.Requests[corev1.ResourceMemory]
-	memoryLimit, memoryLimitFound := t.Spec.Resources.Limits[corev1.ResourceMemory]
-	if !memoryRequestFound {
-		defaultMemoryRequest := resource.MustParse("1Gi")
-		compareResult := memoryLimit.Cmp(defaultMemoryRequest)
-		if memoryLimitFound && compareResult <= 0 {
-			t.Spec.Resources.Requests[corev1.ResourceMemory] = memoryLimit
-		} else {
-			t.Spec.Resources.Requests[corev1.ResourceMemory] = defaultMemoryRequest
-		}
-	}
-
-	podAnnotations := map[string]string{}
-
-	if t.Spec.PodMetadata != nil {
-		if t.Spec.PodMetadata.Labels != nil {
-			for k, v := range t.Spec.PodMetadata.Labels {
-				podLabels[k] = v
-			}
-		}
-		if t.Spec.PodMetadata.Annotations != nil {
-			for k, v := range t.Spec.PodMetadata.Annotations {
-				podAnnotations[k] = v
-			}
-		}
-	}
+	t.Spec.Resources = defaultResources(t.Spec.Resources, defaults)
 
-	dm.Spec.Selector = &metav1.LabelSelector{
-		MatchLabels: podLabels,
-	}
+	dm.Spec.Selector = makeSelector(dm.Spec.Selector, componentQuerier, t.Name)
 	dm.Spec.Replicas = &miniReplicas
+	if t.Spec.Replicas != nil {
+		dm.Spec.Replicas = t.Spec.Replicas
+	}
 
 	thanosArgs := []string{
 		"query",
 		fmt.Sprintf("--query.replica-label=%s", t.Spec.ReplicaLabel),
-		// fmt.Sprintf("--store=dnssrv+%s", t.Spec.StoreDNS),
+	}
+	if t.Spec.StoreDNS != "" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--store=dnssrv+%s", t.Spec.StoreDNS))
+	}
+	var volumemounts []corev1.VolumeMount
+	var volumes []corev1.Volume
+	if t.Spec.FileSD {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--store.sd-files=%s%s", storeSDDir, storeSDFile))
+		volumemounts = append(volumemounts, corev1.VolumeMount{
+			Name:      "store-sd",
+			MountPath: storeSDDir,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "store-sd",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: storeSDConfigMapName(t),
+					},
+				},
+			},
+		})
+	} else {
+		for _, store := range t.Spec.Stores {
+			thanosArgs = append(thanosArgs, fmt.Sprintf("--store=%s", store))
+		}
+		for _, store := range stores {
+			thanosArgs = append(thanosArgs, fmt.Sprintf("--store=%s", storeEndpoint(store, t.Namespace)))
+		}
 	}
 	if t.Spec.LogLevel != "" && t.Spec.LogLevel != "info" {
 		thanosArgs = append(thanosArgs, fmt.Sprintf("--log.level=%s", t.Spec.LogLevel))
@@ -225,40 +312,46 @@ func setQuerierDeployment(
 		},
 	}
 
-	// var livenessProbeHandler corev1.Handler
-	livenessProbeHandler := corev1.Handler{
-		HTTPGet: &corev1.HTTPGetAction{
-			Path: path.Clean("/-/healthy"),
-			Port: intstr.FromString("http"),
-		},
-	}
-	// var livenessProbe *corev1.Probe
-	livenessProbe := &corev1.Probe{
-		Handler:          livenessProbeHandler,
-		PeriodSeconds:    5,
-		FailureThreshold: 120,
-	}
-
+	liveness, readiness := makeProbes(*t.Spec.Image, t.Spec.Probes)
 	containers := []corev1.Container{
 		{
-			Name:          "querier",
-			Image:         *t.Spec.Image,
-			Args:          thanosArgs,
-			LivenessProbe: livenessProbe,
-			Ports:         ports,
+			Name:           "querier",
+			Image:          *t.Spec.Image,
+			LivenessProbe:  liveness,
+			ReadinessProbe: readiness,
+			Args:           thanosArgs,
+			Ports:          ports,
+			Resources:      t.Spec.Resources,
+			VolumeMounts:   volumemounts,
 		},
 	}
 	podspec := corev1.PodSpec{
 		TerminationGracePeriodSeconds: &gracePeriodTerm,
 		Containers:                    containers,
+		Volumes:                       volumes,
+		NodeSelector:                  t.Spec.NodeSelector,
+		Affinity:                      t.Spec.Affinity,
+		Tolerations:                   t.Spec.Tolerations,
+		PriorityClassName:             t.Spec.PriorityClassName,
+		SecurityContext:               t.Spec.SecurityContext,
+	}
+
+	if err := customizePodSpec(
+		&podspec,
+		t.Spec.Secrets,
+		t.Spec.Volumes,
+		t.Spec.VolumeMounts,
+		t.Spec.InitContainers,
+		t.Spec.Containers,
+	); err != nil {
+		return err
 	}
 
 	dm.Spec.Template = corev1.PodTemplateSpec{
-		ObjectMeta: metav1.ObjectMeta{
-			Labels: dm.Spec.Selector.MatchLabels,
-		},
-		Spec: podspec,
+		ObjectMeta: makePodTemplateMetadata(dm.Spec.Selector, t.Spec.PodMetadata, nil, nil),
+		Spec:       podspec,
 	}
+	return nil
 }
 
 // SetStatefulSetService set filds on a appsv1.StatefulSet pointer generated and
@@ -270,113 +363,415 @@ func setQuerierDeployment(
 func setReceiverStatefulSet(
 	ss *appsv1.StatefulSet,
 	service *corev1.Service,
+	defaults corev1.ResourceList,
 	t thanosv1beta1.Receiver,
-) {
+) error {
 	t = *t.DeepCopy()
+	defaultReceiver(&t)
 
-	podLabels := map[string]string{}
-	rl := corev1.ResourceList{}
-
-	switch {
-	case strings.HasPrefix(t.Name, "receiver"):
-		if t.Spec.Storage == "" {
-			t.Spec.Storage = receiveStorage
-		}
-		podLabels["app"] = "receiver"
-		podLabels["thanos"] = t.Name
-		podLabels["thanos-store-api"] = "true"
+	claim, err := makeReceiverVolumeClaimTemplate(t)
+	if err != nil {
+		return err
+	}
+	setVolumeClaimTemplate(ss, claim)
 
-		rl["storage"] = resource.MustParse(t.Spec.Storage)
+	t.Spec.Resources = defaultResources(t.Spec.Resources, defaults)
 
-		ss.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
-			{
-				ObjectMeta: metav1.ObjectMeta{Name: "thanos-persistent-storage"},
-				Spec: corev1.PersistentVolumeClaimSpec{
-					AccessModes: []corev1.PersistentVolumeAccessMode{"ReadWriteOnce"},
-					Resources: corev1.ResourceRequirements{
-						Requests: rl,
-					},
-				},
-			},
-		}
+	ss.Spec.Selector = makeSelector(ss.Spec.Selector, componentReceiver, t.Name)
+	ss.Spec.ServiceName = headlessServiceName(t.Name)
+	ss.Spec.Replicas = &miniReplicas
+	if t.Spec.Replicas != nil {
+		ss.Spec.Replicas = t.Spec.Replicas
 	}
 
-	if t.Spec.Resources.Requests == nil {
-		t.Spec.Resources.Requests = corev1.ResourceList{}
+	podspec, err := makePodSpec(t, ss.Spec.ServiceName)
+	if err != nil {
+		return err
 	}
 
-	_, memoryRequestFound := t.Spec.Resources.Requests[corev1.ResourceMemory]
-	memoryLimit, memoryLimitFound := t.Spec.Resources.Limits[corev1.ResourceMemory]
-	if !memoryRequestFound {
-		defaultMemoryRequest := resource.MustParse("1Gi")
-		compareResult := memoryLimit.Cmp(defaultMemoryRequest)
-		if memoryLimitFound && compareResult <= 0 {
-			t.Spec.Resources.Requests[corev1.ResourceMemory] = memoryLimit
-		} else {
-			t.Spec.Resources.Requests[corev1.ResourceMemory] = defaultMemoryRequest
-		}
+	ss.Spec.Template = corev1.PodTemplateSpec{
+		ObjectMeta: makePodTemplateMetadata(ss.Spec.Selector, t.Spec.PodMetadata, nil, nil),
+		Spec:       *podspec,
 	}
+	return nil
+}
 
-	podAnnotations := map[string]string{}
+// makeReceiverVolumeClaimTemplate returns the claim template of the receiver
+// volumes: volumeClaimTemplate, with what it leaves out taken from storage and
+// storageClassName.
+func makeReceiverVolumeClaimTemplate(t thanosv1beta1.Receiver) (corev1.PersistentVolumeClaim, error) {
+	t = *t.DeepCopy()
+	defaultReceiver(&t)
 
-	if t.Spec.PodMetadata != nil {
-		if t.Spec.PodMetadata.Labels != nil {
-			for k, v := range t.Spec.PodMetadata.Labels {
-				podLabels[k] = v
-			}
+	claim := corev1.PersistentVolumeClaim{}
+	if t.Spec.VolumeClaimTemplate != nil {
+		claim = *t.Spec.VolumeClaimTemplate
+	}
+	claim.Name = "thanos-persistent-storage"
+	if len(claim.Spec.AccessModes) == 0 {
+		claim.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	if claim.Spec.StorageClassName == nil {
+		claim.Spec.StorageClassName = t.Spec.StorageClassName
+	}
+	if _, found := claim.Spec.Resources.Requests[corev1.ResourceStorage]; !found {
+		storage, err := resource.ParseQuantity(t.Spec.Storage)
+		if err != nil {
+			return claim, fmt.Errorf("invalid storage %q: %v", t.Spec.Storage, err)
 		}
-		if t.Spec.PodMetadata.Annotations != nil {
-			for k, v := range t.Spec.PodMetadata.Annotations {
-				podAnnotations[k] = v
-			}
+		if claim.Spec.Resources.Requests == nil {
+			claim.Spec.Resources.Requests = corev1.ResourceList{}
 		}
+		claim.Spec.Resources.Requests[corev1.ResourceStorage] = storage
+	}
+	return claim, nil
+}
+
+// receiverExternalLabels returns the external labels of the receiver,
+// including the receive label of the deprecated receiveLabels field.
+func receiverExternalLabels(t thanosv1beta1.Receiver) map[string]string {
+	labels := map[string]string{}
+	if t.Spec.ReceiveLables != "" {
+		labels["receive"] = t.Spec.ReceiveLables
+	}
+	for k, v := range t.Spec.ExternalLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// makeLabelArgs renders labels into --label flags. Values are quoted the way
+// thanos unquotes them, and flags are sorted so the pod template is stable.
+func makeLabelArgs(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	ss.Spec.Selector = &metav1.LabelSelector{
-		MatchLabels: podLabels,
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, fmt.Sprintf("--label=%s=%s", name, strconv.Quote(labels[name])))
 	}
+	return args
+}
+
+// setCompactorStatefulSet set fields on a appsv1.StatefulSet pointer generated
+// for the Thanos compactor. The compactor must never run concurrently against
+// the same bucket, so the StatefulSet is always a singleton.
+func setCompactorStatefulSet(
+	ss *appsv1.StatefulSet,
+	service *corev1.Service,
+	defaults corev1.ResourceList,
+	t thanosv1beta1.Compactor,
+) error {
+	t = *t.DeepCopy()
+	defaultCompactor(&t)
+
+	t.Spec.Resources = defaultResources(t.Spec.Resources, defaults)
+
+	ss.Spec.Selector = makeSelector(ss.Spec.Selector, componentCompactor, t.Name)
 	ss.Spec.ServiceName = service.Name
 	ss.Spec.Replicas = &miniReplicas
 
-	podspec, err := makePodSpec(t)
+	claim, err := makeVolumeClaimTemplate(t.Spec.Storage)
 	if err != nil {
-		return
+		return err
 	}
+	setVolumeClaimTemplate(ss, claim)
 
-	ss.Spec.Template = corev1.PodTemplateSpec{
-		ObjectMeta: metav1.ObjectMeta{
-			Labels: ss.Spec.Selector.MatchLabels,
+	obs, err := makeObjstore(
+		objstoreConfig(t.Name, t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage),
+		t.Spec.ObjectStorageType,
+		t.Spec.BucketName,
+		t.Spec.SecretName,
+	)
+	if err != nil {
+		return err
+	}
+	thanosArgs := []string{
+		"compact",
+		"--wait",
+		fmt.Sprintf("--data-dir=%s", t.Spec.DataDir),
+	}
+	if t.Spec.RetentionResolutionRaw != "" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--retention.resolution-raw=%s", t.Spec.RetentionResolutionRaw))
+	}
+	if t.Spec.RetentionResolution5m != "" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--retention.resolution-5m=%s", t.Spec.RetentionResolution5m))
+	}
+	if t.Spec.RetentionResolution1h != "" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--retention.resolution-1h=%s", t.Spec.RetentionResolution1h))
+	}
+	if t.Spec.DisableDownsampling {
+		thanosArgs = append(thanosArgs, "--downsampling.disable")
+	}
+	thanosArgs = append(thanosArgs, obs.Args...)
+	if t.Spec.LogLevel != "" && t.Spec.LogLevel != "info" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--log.level=%s", t.Spec.LogLevel))
+	}
+
+	env := obs.Env
+
+	ports := []corev1.ContainerPort{
+		{
+			ContainerPort: 10902,
+			Name:          "http",
+		},
+	}
+
+	// mount to pod
+	volumemounts := []corev1.VolumeMount{
+		{
+			Name:      "thanos-persistent-storage",
+			MountPath: t.Spec.DataDir,
+		},
+	}
+	volumemounts = append(volumemounts, obs.VolumeMounts...)
+
+	liveness, readiness := makeProbes(*t.Spec.Image, t.Spec.Probes)
+	containers := []corev1.Container{
+		{
+			Name:           "compactor",
+			Image:          *t.Spec.Image,
+			LivenessProbe:  liveness,
+			ReadinessProbe: readiness,
+			Args:           thanosArgs,
+			Env:            env,
+			Ports:          ports,
+			Resources:      t.Spec.Resources,
+			VolumeMounts:   volumemounts,
 		},
-		Spec: *podspec,
+	}
+	volumes := obs.Volumes
+
+	podspec := corev1.PodSpec{
+		TerminationGracePeriodSeconds: &gracePeriodTerm,
+		Containers:                    containers,
+		Volumes:                       volumes,
+		NodeSelector:                  t.Spec.NodeSelector,
+		Affinity:                      t.Spec.Affinity,
+		Tolerations:                   t.Spec.Tolerations,
+		PriorityClassName:             t.Spec.PriorityClassName,
+		SecurityContext:               t.Spec.SecurityContext,
+	}
+
+	if err := customizePodSpec(
+		&podspec,
+		t.Spec.Secrets,
+		t.Spec.Volumes,
+		t.Spec.VolumeMounts,
+		t.Spec.InitContainers,
+		t.Spec.Containers,
+	); err != nil {
+		return err
 	}
 
+	ss.Spec.Template = corev1.PodTemplateSpec{
+		ObjectMeta: makePodTemplateMetadata(ss.Spec.Selector, t.Spec.PodMetadata, nil, nil),
+		Spec:       podspec,
+	}
+	return nil
 }
 
-// makePodSpec  is create spec
-func makePodSpec(t thanosv1beta1.Receiver) (*corev1.PodSpec, error) {
+// setRulerStatefulSet set fields on a appsv1.StatefulSet pointer generated
+// for the Thanos ruler. rules is the generated ConfigMap holding every
+// selected rule file, it is mounted into rulesDir.
+func setRulerStatefulSet(
+	ss *appsv1.StatefulSet,
+	service *corev1.Service,
+	defaults corev1.ResourceList,
+	rules *corev1.ConfigMap,
+	t thanosv1beta1.Ruler,
+) error {
+	t = *t.DeepCopy()
+	defaultRuler(&t)
+
+	t.Spec.Resources = defaultResources(t.Spec.Resources, defaults)
+
+	ss.Spec.Selector = makeSelector(ss.Spec.Selector, componentRuler, t.Name)
+	ss.Spec.ServiceName = service.Name
+	ss.Spec.Replicas = &miniReplicas
+
+	claim, err := makeVolumeClaimTemplate(t.Spec.Storage)
+	if err != nil {
+		return err
+	}
+	setVolumeClaimTemplate(ss, claim)
 
-	if t.Spec.ReceivePrefix == "" {
-		t.Spec.ReceivePrefix = receiverDir
+	obs, err := makeObjstore(
+		objstoreConfig(t.Name, t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage),
+		t.Spec.ObjectStorageType,
+		t.Spec.BucketName,
+		t.Spec.SecretName,
+	)
+	if err != nil {
+		return err
+	}
+	thanosArgs := []string{
+		"rule",
+		fmt.Sprintf("--data-dir=%s", t.Spec.DataDir),
+		fmt.Sprintf("--tsdb.retention=%s", t.Spec.Retention),
+		fmt.Sprintf("--rule-file=%s*", rulesDir),
+	}
+	if t.Spec.QuerierRef != nil {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--query=%s.%s.svc:10902", t.Spec.QuerierRef.Name, t.Namespace))
+	}
+	for _, url := range t.Spec.AlertmanagersURLs {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--alertmanagers.url=%s", url))
+	}
+	if t.Spec.EvaluationInterval != "" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--eval-interval=%s", t.Spec.EvaluationInterval))
 	}
-	if t.Spec.Retention == "" {
-		t.Spec.Retention = defaultRetetion
+	thanosArgs = append(thanosArgs, obs.Args...)
+	if t.Spec.LogLevel != "" && t.Spec.LogLevel != "info" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--log.level=%s", t.Spec.LogLevel))
 	}
+
+	env := obs.Env
+
+	ports := []corev1.ContainerPort{
+		{
+			ContainerPort: 10902,
+			Name:          "http",
+		},
+		{
+			ContainerPort: 10901,
+			Name:          "grpc",
+		},
+	}
+
+	// mount to pod
+	volumemounts := []corev1.VolumeMount{
+		{
+			Name:      "thanos-persistent-storage",
+			MountPath: t.Spec.DataDir,
+		},
+		{
+			Name:      "rules",
+			MountPath: rulesDir,
+		},
+	}
+	volumemounts = append(volumemounts, obs.VolumeMounts...)
+
+	liveness, readiness := makeProbes(*t.Spec.Image, t.Spec.Probes)
+	containers := []corev1.Container{
+		{
+			Name:           "ruler",
+			Image:          *t.Spec.Image,
+			LivenessProbe:  liveness,
+			ReadinessProbe: readiness,
+			Args:           thanosArgs,
+			Env:            env,
+			Ports:          ports,
+			Resources:      t.Spec.Resources,
+			VolumeMounts:   volumemounts,
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "rules",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: rules.Name,
+					},
+				},
+			},
+		},
+	}
+	volumes = append(volumes, obs.Volumes...)
+
+	podspec := corev1.PodSpec{
+		TerminationGracePeriodSeconds: &gracePeriodTerm,
+		Containers:                    containers,
+		Volumes:                       volumes,
+		NodeSelector:                  t.Spec.NodeSelector,
+		Affinity:                      t.Spec.Affinity,
+		Tolerations:                   t.Spec.Tolerations,
+		PriorityClassName:             t.Spec.PriorityClassName,
+		SecurityContext:               t.Spec.SecurityContext,
+	}
+
+	if err := customizePodSpec(
+		&podspec,
+		t.Spec.Secrets,
+		t.Spec.Volumes,
+		t.Spec.VolumeMounts,
+		t.Spec.InitContainers,
+		t.Spec.Containers,
+	); err != nil {
+		return err
+	}
+
+	ss.Spec.Template = corev1.PodTemplateSpec{
+		ObjectMeta: makePodTemplateMetadata(ss.Spec.Selector, t.Spec.PodMetadata, nil, nil),
+		Spec:       podspec,
+	}
+	return nil
+}
+
+// makeRuleConfigMap renders every key of the selected rule ConfigMaps into
+// a single ConfigMap. Keys are prefixed with the source ConfigMap name so
+// files from different sources never collide.
+func makeRuleConfigMap(cm *corev1.ConfigMap, role string, sources []corev1.ConfigMap) {
+	cm.Labels = map[string]string{
+		"thanos": role,
+	}
+	cm.Data = map[string]string{}
+	for _, src := range sources {
+		for k, v := range src.Data {
+			cm.Data[fmt.Sprintf("%s-%s", src.Name, k)] = v
+		}
+	}
+}
+
+// makePodSpec  is create spec
+// serviceName is the governing service providing the stable pod DNS names
+// used in the hashring when more than one replica is deployed.
+func makePodSpec(t thanosv1beta1.Receiver, serviceName string) (*corev1.PodSpec, error) {
 	// TODO set args to spec
+	obs, err := makeObjstore(
+		objstoreConfig(t.Name, t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage),
+		t.Spec.ObjectStorageType,
+		t.Spec.BucketName,
+		t.Spec.SecretName,
+	)
+	if err != nil {
+		return nil, err
+	}
 	thanosArgs := []string{
 		"receive",
 		fmt.Sprintf("--tsdb.path=%s", t.Spec.ReceivePrefix),
 		fmt.Sprintf("--tsdb.retention=%s", t.Spec.Retention),
-		fmt.Sprintf("--labels=receive=\"%s\"", t.Spec.ReceiveLables),
-		fmt.Sprintf("--objstore.config=type: %s\nconfig:\n  bucket: \"%s\"", t.Spec.ObjectStorageType, t.Spec.BucketName),
 	}
+	thanosArgs = append(thanosArgs, makeLabelArgs(receiverExternalLabels(t))...)
+	thanosArgs = append(thanosArgs, obs.Args...)
 	if t.Spec.LogLevel != "" && t.Spec.LogLevel != "info" {
 		thanosArgs = append(thanosArgs, fmt.Sprintf("--log.level=%s", t.Spec.LogLevel))
 	}
-	env := []corev1.EnvVar{
-		{
-			Name:  "GOOGLE_APPLICATION_CREDENTIALS",
-			Value: secretsDir + t.Spec.SecretName + ".json",
-		},
+	env := obs.Env
+
+	withHashring := receiverNeedsHashring(t)
+	if withHashring {
+		thanosArgs = append(thanosArgs,
+			fmt.Sprintf("--receive.hashrings-file=%s%s", hashringsDir, hashringsFile),
+			fmt.Sprintf("--receive.local-endpoint=%s", receiveEndpoint("$(POD_NAME)", serviceName, t.Namespace)),
+			fmt.Sprintf("--receive.replication-factor=%d", receiverReplicationFactor(t)),
+		)
+		if len(t.Spec.Tenants) > 0 {
+			tenantHeader := defaultTenantHeader
+			if t.Spec.TenantHeader != "" {
+				tenantHeader = t.Spec.TenantHeader
+			}
+			thanosArgs = append(thanosArgs, fmt.Sprintf("--receive.tenant-header=%s", tenantHeader))
+		}
+		env = append(env, corev1.EnvVar{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		})
 	}
 
 	ports := []corev1.ContainerPort{
@@ -388,67 +783,230 @@ func makePodSpec(t thanosv1beta1.Receiver) (*corev1.PodSpec, error) {
 			ContainerPort: 10901,
 			Name:          "grpc",
 		},
-	}
-
-	if strings.Contains(t.Name, "receiver") {
-		ports = append(ports, corev1.ContainerPort{
+		{
 			ContainerPort: 19291,
 			Name:          "receive",
-		})
+		},
 	}
 
 	// mount to pod
 	volumemounts := []corev1.VolumeMount{
 		{
 			Name:      "thanos-persistent-storage",
-			MountPath: t.Spec.Retention,
-		},
-		{
-			Name:      "google-cloud-key",
-			MountPath: secretsDir,
+			MountPath: t.Spec.ReceivePrefix,
 		},
 	}
+	volumemounts = append(volumemounts, obs.VolumeMounts...)
+
+	if withHashring {
+		volumemounts = append(volumemounts, corev1.VolumeMount{
+			Name:      "hashrings",
+			MountPath: hashringsDir,
+		})
+	}
 
+	liveness, readiness := makeProbes(*t.Spec.Image, t.Spec.Probes)
 	containers := []corev1.Container{
 		{
-			Name:         "receiver",
-			Image:        *t.Spec.Image,
-			Args:         thanosArgs,
-			Env:          env,
-			Ports:        ports,
-			VolumeMounts: volumemounts,
+			Name:           "receiver",
+			Image:          *t.Spec.Image,
+			LivenessProbe:  liveness,
+			ReadinessProbe: readiness,
+			Args:           thanosArgs,
+			Env:            env,
+			Ports:          ports,
+			Resources:      t.Spec.Resources,
+			VolumeMounts:   volumemounts,
 		},
 	}
 
-	// Need create json from gcp iam
-	// https://github.com/orangesys/blueprint/tree/master/prometheus-thanos
-	// kubectl create secret generic ${SERVICE_ACCOUNT_NAME} --from-file=${SERVICE_ACCOUNT_NAME}.json=${SERVICE_ACCOUNT_NAME}.json
-	// secret name is thanos-demo-gcs
-	// TODO setting secret name with spec
-	volumes := []corev1.Volume{
-		{
-			Name: "google-cloud-key",
+	volumes := obs.Volumes
+
+	if withHashring {
+		volumes = append(volumes, corev1.Volume{
+			Name: "hashrings",
 			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: t.Spec.SecretName,
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: hashringConfigMapName(t),
+					},
 				},
 			},
-		},
+		})
 	}
 
-	return &corev1.PodSpec{
+	podspec := corev1.PodSpec{
 		TerminationGracePeriodSeconds: &gracePeriodTerm,
 		Containers:                    containers,
 		Volumes:                       volumes,
-	}, nil
+		NodeSelector:                  t.Spec.NodeSelector,
+		Affinity:                      t.Spec.Affinity,
+		Tolerations:                   t.Spec.Tolerations,
+		PriorityClassName:             t.Spec.PriorityClassName,
+		SecurityContext:               t.Spec.SecurityContext,
+	}
+
+	if err := customizePodSpec(
+		&podspec,
+		t.Spec.Secrets,
+		t.Spec.Volumes,
+		t.Spec.VolumeMounts,
+		t.Spec.InitContainers,
+		t.Spec.Containers,
+	); err != nil {
+		return nil, err
+	}
+
+	return &podspec, nil
 }
 
-func makeService(service *corev1.Service, role string) {
-	if strings.Contains(role, "receiver") {
-		service.Labels = map[string]string{
-			"service": "receiver",
-			"thanos":  role,
+// injectSidecar adds the thanos sidecar container described by t to a
+// Prometheus pod. Pods which already run a sidecar are left untouched, pods
+// are left untouched too when t is invalid.
+func injectSidecar(pod *corev1.Pod, defaults corev1.ResourceList, t thanosv1beta1.Sidecar) error {
+	t = *t.DeepCopy()
+	defaultSidecar(&t)
+	t.Spec.Resources = defaultResources(t.Spec.Resources, defaults)
+
+	for _, c := range pod.Spec.Containers {
+		if c.Name == sidecarName {
+			return nil
 		}
+	}
+
+	obs, err := makeObjstore(
+		objstoreConfig(t.Name, t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage),
+		t.Spec.ObjectStorageType,
+		t.Spec.BucketName,
+		t.Spec.SecretName,
+	)
+	if err != nil {
+		return err
+	}
+	thanosArgs := []string{
+		"sidecar",
+		fmt.Sprintf("--prometheus.url=%s", t.Spec.PrometheusURL),
+		fmt.Sprintf("--tsdb.path=%s", t.Spec.TSDBPath),
+	}
+	if t.Spec.ReloaderConfigFile != "" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--reloader.config-file=%s", t.Spec.ReloaderConfigFile))
+	}
+	if t.Spec.ReloaderConfigEnvsubstFile != "" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--reloader.config-envsubst-file=%s", t.Spec.ReloaderConfigEnvsubstFile))
+	}
+	thanosArgs = append(thanosArgs, obs.Args...)
+	if t.Spec.LogLevel != "" && t.Spec.LogLevel != "info" {
+		thanosArgs = append(thanosArgs, fmt.Sprintf("--log.level=%s", t.Spec.LogLevel))
+	}
+
+	env := append(obs.Env,
+		corev1.EnvVar{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+		corev1.EnvVar{
+			Name: "POD_NAMESPACE",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+			},
+		},
+	)
+
+	ports := []corev1.ContainerPort{
+		{
+			ContainerPort: 10902,
+			Name:          "http",
+		},
+		{
+			ContainerPort: 10901,
+			Name:          "grpc",
+		},
+	}
+
+	// mount to pod
+	volumemounts := obs.VolumeMounts
+	if t.Spec.TSDBVolumeName != "" {
+		volumemounts = append(volumemounts, corev1.VolumeMount{
+			Name:      t.Spec.TSDBVolumeName,
+			MountPath: t.Spec.TSDBPath,
+		})
+	}
+
+	liveness, readiness := makeProbes(*t.Spec.Image, t.Spec.Probes)
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+		Name:           sidecarName,
+		Image:          *t.Spec.Image,
+		LivenessProbe:  liveness,
+		ReadinessProbe: readiness,
+		Args:           thanosArgs,
+		Env:            env,
+		Ports:          ports,
+		Resources:      t.Spec.Resources,
+		VolumeMounts:   volumemounts,
+	})
+	pod.Spec.Volumes = append(pod.Spec.Volumes, obs.Volumes...)
+	return nil
+}
+
+// refuseAdoption returns an error when obj exists without being controlled
+// by owner, such as a Service created by the user under the same name, so it
+// is not overwritten.
+func refuseAdoption(obj, owner metav1.Object) error {
+	created := obj.GetCreationTimestamp()
+	if created.IsZero() || metav1.IsControlledBy(obj, owner) {
+		return nil
+	}
+	return fmt.Errorf("%s already exists and is not managed by %s", obj.GetName(), owner.GetName())
+}
+
+// headlessServiceName returns the name of the headless Service listing
+// every pod of the named component
+func headlessServiceName(name string) string {
+	return name + "-headless"
+}
+
+// makeHeadlessService set fields on the headless Service listing every pod
+// of the component named name. It gives the pods of a StatefulSet stable DNS
+// names and lets queriers discover each of them.
+func makeHeadlessService(service *corev1.Service, component, name string) {
+	makeService(service, component, name, nil)
+	service.Spec.ClusterIP = corev1.ClusterIPNone
+}
+
+// makeSidecarService exposes the StoreAPI of every pod selected by a Sidecar
+func makeSidecarService(service *corev1.Service, t thanosv1beta1.Sidecar) {
+	setServiceMetadata(service, t.Spec.ServiceMetadata, map[string]string{
+		"service": "sidecar",
+		"thanos":  t.Name,
+	})
+	service.Spec.ClusterIP = corev1.ClusterIPNone
+	service.Spec.Ports = []corev1.ServicePort{
+		{
+			Port: 10902,
+			Name: "http",
+		},
+		{
+			Port: 10901,
+			Name: "grpc",
+		},
+	}
+	service.Spec.Selector = nil
+	if t.Spec.Selector != nil {
+		service.Spec.Selector = t.Spec.Selector.MatchLabels
+	}
+}
+
+// makeService set fields on the Service exposing the component named name.
+// metadata holds the user labels and annotations of the Service.
+func makeService(service *corev1.Service, component, name string, metadata *metav1.ObjectMeta) {
+	setServiceMetadata(service, metadata, map[string]string{
+		"service": component,
+		"thanos":  name,
+	})
+	switch component {
+	case componentReceiver:
 		service.Spec.Ports = []corev1.ServicePort{
 			{
 				Port: 19291,
@@ -463,28 +1021,14 @@ func makeService(service *corev1.Service, role string) {
 				Name: "grpc",
 			},
 		}
-	}
-	if strings.Contains(role, "querier") {
-		service.Labels = map[string]string{
-			"service": "querier",
-			"thanos":  role,
-		}
+	case componentCompactor:
 		service.Spec.Ports = []corev1.ServicePort{
 			{
 				Port: 10902,
 				Name: "http",
 			},
-			{
-				Port: 10901,
-				Name: "grpc",
-			},
-		}
-	}
-	if strings.Contains(role, "store") {
-		service.Labels = map[string]string{
-			"service": "store",
-			"thanos":  role,
 		}
+	default:
 		service.Spec.Ports = []corev1.ServicePort{
 			{
 				Port: 10902,
@@ -496,7 +1040,182 @@ func makeService(service *corev1.Service, role string) {
 			},
 		}
 	}
-	service.Spec.Selector = map[string]string{"thanos": role}
+	service.Spec.Selector = map[string]string{
+		"app":    component,
+		"thanos": name,
+	}
+}
+
+// setServiceMetadata sets the labels of service to those of metadata and
+// labels, the latter taking precedence. Annotations of metadata are added to
+// the existing ones, which load balancer controllers also write to.
+func setServiceMetadata(service *corev1.Service, metadata *metav1.ObjectMeta, labels map[string]string) {
+	service.Labels = map[string]string{}
+	if metadata != nil {
+		for k, v := range metadata.Labels {
+			service.Labels[k] = v
+		}
+		if len(metadata.Annotations) > 0 && service.Annotations == nil {
+			service.Annotations = map[string]string{}
+		}
+		for k, v := range metadata.Annotations {
+			service.Annotations[k] = v
+		}
+	}
+	for k, v := range labels {
+		service.Labels[k] = v
+	}
+}
+
+// makeSelector returns the selector of the workload running the component
+// named name. Selectors are immutable, so a workload keeps the one it was
+// created with: those created by earlier versions also select the labels of
+// podMetadata.
+func makeSelector(selector *metav1.LabelSelector, component, name string) *metav1.LabelSelector {
+	if selector != nil {
+		return selector
+	}
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app":    component,
+			"thanos": name,
+		},
+	}
+}
+
+// makePodTemplateMetadata returns the metadata of the pods matched by
+// selector: the labels and annotations of podMetadata, overridden by those
+// set by the operator.
+func makePodTemplateMetadata(
+	selector *metav1.LabelSelector,
+	podMetadata *metav1.ObjectMeta,
+	labels map[string]string,
+	annotations map[string]string,
+) metav1.ObjectMeta {
+	meta := metav1.ObjectMeta{
+		Labels: map[string]string{},
+	}
+	if podMetadata != nil {
+		for k, v := range podMetadata.Labels {
+			meta.Labels[k] = v
+		}
+		for k, v := range podMetadata.Annotations {
+			if meta.Annotations == nil {
+				meta.Annotations = map[string]string{}
+			}
+			meta.Annotations[k] = v
+		}
+	}
+	for k, v := range labels {
+		meta.Labels[k] = v
+	}
+	for k, v := range selector.MatchLabels {
+		meta.Labels[k] = v
+	}
+	for k, v := range annotations {
+		if meta.Annotations == nil {
+			meta.Annotations = map[string]string{}
+		}
+		meta.Annotations[k] = v
+	}
+	return meta
+}
+
+// customizePodSpec adds the secrets, volumes, volume mounts, init containers
+// and containers given by the user to podspec, whose first container runs
+// thanos. Each secret is mounted into userSecretsDir/<secret-name>, apart
+// from the legacy object storage key mounted at secretsDir.
+func customizePodSpec(
+	podspec *corev1.PodSpec,
+	secrets []string,
+	volumes []corev1.Volume,
+	volumeMounts []corev1.VolumeMount,
+	initContainers []corev1.Container,
+	containers []corev1.Container,
+) error {
+	thanos := &podspec.Containers[0]
+	for _, secret := range secrets {
+		name := secretVolumeName(secret)
+		podspec.Volumes = append(podspec.Volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secret,
+				},
+			},
+		})
+		thanos.VolumeMounts = append(thanos.VolumeMounts, corev1.VolumeMount{
+			Name:      name,
+			MountPath: userSecretsDir + secret,
+			ReadOnly:  true,
+		})
+	}
+	podspec.Volumes = append(podspec.Volumes, volumes...)
+	thanos.VolumeMounts = append(thanos.VolumeMounts, volumeMounts...)
+	podspec.InitContainers = append(podspec.InitContainers, initContainers...)
+
+	merged, err := mergeContainers(podspec.Containers, containers)
+	if err != nil {
+		return err
+	}
+	podspec.Containers = merged
+	return nil
+}
+
+// secretVolumeName returns the name of the volume of the named Secret. Names
+// which are not valid volume names, being too long or holding dots, are
+// shortened and given a hash of the Secret name to keep them unique.
+func secretVolumeName(secret string) string {
+	name := "secret-" + secret
+	if len(name) <= validation.DNS1123LabelMaxLength && !strings.Contains(name, ".") {
+		return name
+	}
+	sum := sha256.Sum256([]byte(secret))
+	suffix := "-" + hex.EncodeToString(sum[:])[:8]
+	name = strings.Replace(name, ".", "-", -1)
+	if max := validation.DNS1123LabelMaxLength - len(suffix); len(name) > max {
+		name = name[:max]
+	}
+	return name + suffix
+}
+
+// mergeContainers applies patches to the containers of the same name as a
+// strategic merge patch, the way kubectl would. Patches matching no container
+// are appended.
+func mergeContainers(containers, patches []corev1.Container) ([]corev1.Container, error) {
+	var merged []corev1.Container
+	patched := map[string]bool{}
+	for _, container := range containers {
+		for _, patch := range patches {
+			if patch.Name != container.Name {
+				continue
+			}
+			original, err := json.Marshal(container)
+			if err != nil {
+				return nil, err
+			}
+			data, err := json.Marshal(patch)
+			if err != nil {
+				return nil, err
+			}
+			result, err := strategicpatch.StrategicMergePatch(original, data, corev1.Container{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to merge container %q: %v", container.Name, err)
+			}
+			container = corev1.Container{}
+			if err := json.Unmarshal(result, &container); err != nil {
+				return nil, err
+			}
+			patched[patch.Name] = true
+		}
+		merged = append(merged, container)
+	}
+	for _, patch := range patches {
+		if !patched[patch.Name] {
+			merged = append(merged, patch)
+		}
+	}
+	return merged, nil
 }
 
 func ignoreNotFound(err error) error {
@@ -0,0 +1,724 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// servicePortNames returns the names of the ports of service
+func servicePortNames(service *corev1.Service) []string {
+	var names []string
+	for _, port := range service.Spec.Ports {
+		names = append(names, port.Name)
+	}
+	return names
+}
+
+// containerPortNames returns the names of the ports of container
+func containerPortNames(container corev1.Container) []string {
+	var names []string
+	for _, port := range container.Ports {
+		names = append(names, port.Name)
+	}
+	return names
+}
+
+var _ = Describe("Component shape", func() {
+	Context("makeService", func() {
+		It("should shape the Service after the component, not the name", func() {
+			service := &corev1.Service{}
+			makeService(service, componentStore, "my-receiver-store", nil)
+
+			Expect(servicePortNames(service)).To(ConsistOf("http", "grpc"))
+			Expect(service.Labels).To(HaveKeyWithValue("service", componentStore))
+			Expect(service.Spec.Selector).To(Equal(map[string]string{
+				"app":    componentStore,
+				"thanos": "my-receiver-store",
+			}))
+		})
+
+		It("should expose the receive port for receivers of any name", func() {
+			service := &corev1.Service{}
+			makeService(service, componentReceiver, "ingest", nil)
+
+			Expect(servicePortNames(service)).To(ConsistOf("receive", "http", "grpc"))
+			Expect(service.Labels).To(HaveKeyWithValue("service", componentReceiver))
+		})
+
+		It("should propagate the service metadata without losing its own labels", func() {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"existing": "kept"},
+				},
+			}
+			makeService(service, componentQuerier, "querier", &metav1.ObjectMeta{
+				Labels:      map[string]string{"team": "observability", "service": "other"},
+				Annotations: map[string]string{"lb": "internal"},
+			})
+
+			Expect(service.Labels).To(HaveKeyWithValue("team", "observability"))
+			Expect(service.Labels).To(HaveKeyWithValue("service", componentQuerier))
+			Expect(service.Annotations).To(Equal(map[string]string{"existing": "kept", "lb": "internal"}))
+		})
+
+		It("should only expose http for compactors", func() {
+			service := &corev1.Service{}
+			makeService(service, componentCompactor, "querier-compactor", nil)
+
+			Expect(servicePortNames(service)).To(ConsistOf("http"))
+		})
+	})
+
+	Context("makeProbes", func() {
+		It("should probe the default image", func() {
+			liveness, readiness := makeProbes(*thanosImage("", "", ""), nil)
+
+			Expect(liveness).NotTo(BeNil())
+			Expect(liveness.HTTPGet.Path).To(Equal("/-/healthy"))
+			Expect(readiness).NotTo(BeNil())
+			Expect(readiness.HTTPGet.Path).To(Equal("/-/ready"))
+		})
+
+		It("should not probe releases without the probe endpoints", func() {
+			liveness, readiness := makeProbes("improbable/thanos:v0.5.0", nil)
+
+			Expect(liveness).To(BeNil())
+			Expect(readiness).To(BeNil())
+		})
+
+		It("should probe images not tagged with a release", func() {
+			for _, image := range []string{
+				"quay.io/thanos/thanos:master-2019-11-20-abcdef0",
+				"localhost:5000/thanos",
+			} {
+				liveness, readiness := makeProbes(image, nil)
+
+				Expect(liveness).NotTo(BeNil(), image)
+				Expect(readiness).NotTo(BeNil(), image)
+			}
+		})
+	})
+
+	Context("setCompactorStatefulSet", func() {
+		It("should leave the claim templates of existing statefulsets alone", func() {
+			compactor := thanosv1beta1.Compactor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "compactor",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.CompactorSpec{
+					BucketName: "metrics",
+					Storage:    "20Gi",
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+			ss.CreationTimestamp = metav1.Now()
+			ss.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{}}
+
+			Expect(setCompactorStatefulSet(ss, &corev1.Service{}, nil, compactor)).To(Succeed())
+
+			Expect(ss.Spec.VolumeClaimTemplates).To(Equal([]corev1.PersistentVolumeClaim{{}}))
+		})
+
+		It("should schedule the pods as requested", func() {
+			compactor := thanosv1beta1.Compactor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "compactor",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.CompactorSpec{
+					BucketName:        "metrics",
+					Tolerations:       []corev1.Toleration{{Key: "dedicated", Value: "thanos"}},
+					PriorityClassName: "long-term-storage",
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+
+			Expect(setCompactorStatefulSet(ss, &corev1.Service{}, nil, compactor)).To(Succeed())
+
+			Expect(ss.Spec.Template.Spec.Tolerations).To(Equal(compactor.Spec.Tolerations))
+			Expect(ss.Spec.Template.Spec.PriorityClassName).To(Equal("long-term-storage"))
+		})
+	})
+
+	Context("setRulerStatefulSet", func() {
+		It("should leave the claim templates of existing statefulsets alone", func() {
+			ruler := thanosv1beta1.Ruler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ruler",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.RulerSpec{
+					BucketName: "metrics",
+					Storage:    "20Gi",
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+			ss.CreationTimestamp = metav1.Now()
+			ss.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{}}
+
+			Expect(setRulerStatefulSet(ss, &corev1.Service{}, nil, &corev1.ConfigMap{}, ruler)).To(Succeed())
+
+			Expect(ss.Spec.VolumeClaimTemplates).To(Equal([]corev1.PersistentVolumeClaim{{}}))
+		})
+	})
+
+	Context("setReceiverStatefulSet", func() {
+		It("should create the volume and receive port for any name", func() {
+			image := "improbable/thanos:v0.5.0"
+			receiver := thanosv1beta1.Receiver{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingest",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.ReceiverSpec{
+					BucketName: "metrics",
+					Image:      &image,
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+
+			Expect(setReceiverStatefulSet(ss, &corev1.Service{}, nil, receiver)).To(Succeed())
+
+			Expect(ss.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			Expect(ss.Spec.Selector.MatchLabels).To(HaveKeyWithValue("app", componentReceiver))
+			Expect(ss.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(containerPortNames(ss.Spec.Template.Spec.Containers[0])).To(ContainElement("receive"))
+		})
+
+		It("should be governed by the headless Service of the receiver", func() {
+			receiver := thanosv1beta1.Receiver{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingest",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.ReceiverSpec{
+					BucketName: "metrics",
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+
+			Expect(setReceiverStatefulSet(ss, &corev1.Service{}, nil, receiver)).To(Succeed())
+
+			Expect(ss.Spec.ServiceName).To(Equal("ingest-headless"))
+		})
+	})
+
+	Context("makeHeadlessService", func() {
+		It("should list every pod of the component", func() {
+			service := &corev1.Service{}
+
+			makeHeadlessService(service, componentReceiver, "ingest")
+
+			Expect(service.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+			Expect(service.Spec.Selector).To(Equal(map[string]string{
+				"app":    componentReceiver,
+				"thanos": "ingest",
+			}))
+			Expect(servicePortNames(service)).To(ContainElement("grpc"))
+		})
+	})
+
+	Context("refuseAdoption", func() {
+		var receiver *thanosv1beta1.Receiver
+
+		BeforeEach(func() {
+			receiver = &thanosv1beta1.Receiver{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingest",
+					Namespace: "default",
+					UID:       "receiver-uid",
+				},
+			}
+		})
+
+		It("should accept Services yet to be created", func() {
+			Expect(refuseAdoption(&corev1.Service{}, receiver)).To(Succeed())
+		})
+
+		It("should refuse Services it does not control", func() {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "ingest-headless",
+					CreationTimestamp: metav1.Now(),
+				},
+			}
+
+			Expect(refuseAdoption(service, receiver)).NotTo(Succeed())
+		})
+
+		It("should accept Services it controls", func() {
+			controller := true
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "ingest-headless",
+					CreationTimestamp: metav1.Now(),
+					OwnerReferences: []metav1.OwnerReference{{
+						Name:       receiver.Name,
+						UID:        receiver.UID,
+						Controller: &controller,
+					}},
+				},
+			}
+
+			Expect(refuseAdoption(service, receiver)).To(Succeed())
+		})
+	})
+
+	Context("makeLabelArgs", func() {
+		It("should render sorted and quoted label flags", func() {
+			receiver := thanosv1beta1.Receiver{
+				Spec: thanosv1beta1.ReceiverSpec{
+					BucketName:    "metrics",
+					ReceiveLables: "legacy",
+					ExternalLabels: map[string]string{
+						"tenant":  `team "a"`,
+						"cluster": "eu-1",
+					},
+				},
+			}
+
+			Expect(makeLabelArgs(receiverExternalLabels(receiver))).To(Equal([]string{
+				`--label=cluster="eu-1"`,
+				`--label=receive="legacy"`,
+				`--label=tenant="team \"a\""`,
+			}))
+		})
+	})
+
+	Context("makeReceiverVolumeClaimTemplate", func() {
+		It("should mount the volume at the receive prefix with the storage class", func() {
+			image := "improbable/thanos:v0.5.0"
+			class := "fast"
+			receiver := thanosv1beta1.Receiver{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingest",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.ReceiverSpec{
+					BucketName:       "metrics",
+					Image:            &image,
+					Retention:        "24h",
+					Storage:          "10Gi",
+					StorageClassName: &class,
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+
+			Expect(setReceiverStatefulSet(ss, &corev1.Service{}, nil, receiver)).To(Succeed())
+
+			claim := ss.Spec.VolumeClaimTemplates[0]
+			Expect(claim.Spec.StorageClassName).To(Equal(&class))
+			storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+			Expect(storage.String()).To(Equal("10Gi"))
+			Expect(ss.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name:      "thanos-persistent-storage",
+				MountPath: receiverDir,
+			}))
+		})
+
+		It("should prefer the storage of the volume claim template", func() {
+			receiver := thanosv1beta1.Receiver{
+				Spec: thanosv1beta1.ReceiverSpec{
+					BucketName: "metrics",
+					Storage:    "10Gi",
+					VolumeClaimTemplate: &corev1.PersistentVolumeClaim{
+						Spec: corev1.PersistentVolumeClaimSpec{
+							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceStorage: resource.MustParse("20Gi"),
+								},
+							},
+						},
+					},
+				},
+			}
+
+			claim, err := makeReceiverVolumeClaimTemplate(receiver)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claim.Name).To(Equal("thanos-persistent-storage"))
+			Expect(claim.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteMany))
+			storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+			Expect(storage.String()).To(Equal("20Gi"))
+		})
+	})
+
+	Context("setStoreDeployment", func() {
+		It("should not expose the receive port for stores named like receivers", func() {
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-receiver-store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+				},
+			}
+			dm := &appsv1.Deployment{}
+
+			Expect(setStoreDeployment(dm, nil, storeShards(store)[0], store)).To(Succeed())
+
+			Expect(dm.Spec.Selector.MatchLabels).To(HaveKeyWithValue("app", componentStore))
+			Expect(dm.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(containerPortNames(dm.Spec.Template.Spec.Containers[0])).NotTo(ContainElement("receive"))
+		})
+
+		It("should keep the pod metadata out of the selector", func() {
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					PodMetadata: &metav1.ObjectMeta{
+						Labels:      map[string]string{"team": "observability"},
+						Annotations: map[string]string{"scrape": "true"},
+					},
+				},
+			}
+			dm := &appsv1.Deployment{}
+
+			Expect(setStoreDeployment(dm, nil, storeShards(store)[0], store)).To(Succeed())
+
+			Expect(dm.Spec.Selector.MatchLabels).To(Equal(map[string]string{
+				"app":    componentStore,
+				"thanos": "store",
+			}))
+			Expect(dm.Spec.Template.Labels).To(HaveKeyWithValue("team", "observability"))
+			Expect(dm.Spec.Template.Labels).To(HaveKeyWithValue("app", componentStore))
+			Expect(dm.Spec.Template.Annotations).To(HaveKeyWithValue("scrape", "true"))
+		})
+
+		It("should keep the selector of existing deployments", func() {
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+				},
+			}
+			selector := map[string]string{
+				"app":    componentStore,
+				"thanos": "store",
+				"team":   "observability",
+			}
+			dm := &appsv1.Deployment{}
+			dm.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+
+			Expect(setStoreDeployment(dm, nil, storeShards(store)[0], store)).To(Succeed())
+
+			Expect(dm.Spec.Selector.MatchLabels).To(Equal(selector))
+			Expect(dm.Spec.Template.Labels).To(Equal(selector))
+		})
+
+		It("should merge user containers by name and mount the secrets", func() {
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					Secrets:    []string{"ca-bundle"},
+					Containers: []corev1.Container{
+						{
+							Name: "store",
+							Env:  []corev1.EnvVar{{Name: "SSL_CERT_DIR", Value: "/etc/thanos/user-secrets/ca-bundle"}},
+						},
+						{
+							Name:  "proxy",
+							Image: "proxy:latest",
+						},
+					},
+				},
+			}
+			dm := &appsv1.Deployment{}
+
+			Expect(setStoreDeployment(dm, nil, storeShards(store)[0], store)).To(Succeed())
+
+			containers := dm.Spec.Template.Spec.Containers
+			Expect(containers).To(HaveLen(2))
+			Expect(containers[0].Name).To(Equal("store"))
+			Expect(containers[0].Args).NotTo(BeEmpty())
+			Expect(containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "SSL_CERT_DIR", Value: "/etc/thanos/user-secrets/ca-bundle"}))
+			Expect(containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name:      "secret-ca-bundle",
+				MountPath: "/etc/thanos/user-secrets/ca-bundle",
+				ReadOnly:  true,
+			}))
+			Expect(containers[1].Image).To(Equal("proxy:latest"))
+		})
+
+		It("should mount the secrets apart from the object storage key under valid volume names", func() {
+			long := strings.Repeat("tls-", 20) + "bundle"
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					SecretName: "gcs-key",
+					Secrets:    []string{"ca.example.com", long},
+				},
+			}
+			dm := &appsv1.Deployment{}
+
+			Expect(setStoreDeployment(dm, nil, storeShards(store)[0], store)).To(Succeed())
+
+			mounts := map[string]string{}
+			for _, mount := range dm.Spec.Template.Spec.Containers[0].VolumeMounts {
+				mounts[mount.MountPath] = mount.Name
+			}
+			Expect(mounts).To(HaveKeyWithValue(secretsDir, "google-cloud-key"))
+			Expect(mounts).To(HaveKey(userSecretsDir + "ca.example.com"))
+			Expect(mounts).To(HaveKey(userSecretsDir + long))
+			for path := range mounts {
+				if path != secretsDir {
+					Expect(path).NotTo(HavePrefix(secretsDir))
+				}
+			}
+			for _, volume := range dm.Spec.Template.Spec.Volumes {
+				Expect(validation.IsDNS1123Label(volume.Name)).To(BeEmpty(), volume.Name)
+			}
+			Expect(secretVolumeName("ca.example.com")).NotTo(Equal(secretVolumeName("ca-example-com")))
+		})
+
+		It("should probe the store health and readiness", func() {
+			failureThreshold := int32(10)
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					Probes: &thanosv1beta1.Probes{
+						Readiness: &thanosv1beta1.ProbeSettings{
+							FailureThreshold: &failureThreshold,
+						},
+					},
+				},
+			}
+			dm := &appsv1.Deployment{}
+
+			Expect(setStoreDeployment(dm, nil, storeShards(store)[0], store)).To(Succeed())
+
+			container := dm.Spec.Template.Spec.Containers[0]
+			Expect(container.LivenessProbe.HTTPGet.Path).To(Equal("/-/healthy"))
+			Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal("/-/ready"))
+			Expect(container.ReadinessProbe.FailureThreshold).To(Equal(failureThreshold))
+			Expect(container.ReadinessProbe.PeriodSeconds).To(BeEquivalentTo(5))
+		})
+
+		It("should default the requests the store leaves out, capped to its limits", func() {
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("512Mi"),
+						},
+					},
+				},
+			}
+			defaults, err := ParseResourceList("cpu=100m,memory=1Gi")
+			Expect(err).NotTo(HaveOccurred())
+			dm := &appsv1.Deployment{}
+
+			Expect(setStoreDeployment(dm, defaults, storeShards(store)[0], store)).To(Succeed())
+
+			requests := dm.Spec.Template.Spec.Containers[0].Resources.Requests
+			Expect(requests.Cpu().String()).To(Equal("100m"))
+			Expect(requests.Memory().String()).To(Equal("512Mi"))
+		})
+
+		It("should fan sharded stores out into disjoint shards", func() {
+			hashmodShards := int32(2)
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					Shards: &thanosv1beta1.StoreShards{
+						HashmodShards: &hashmodShards,
+						TimePartitions: []thanosv1beta1.StoreTimePartition{
+							{MinTime: "-2w"},
+							{MaxTime: "-2w"},
+						},
+					},
+				},
+			}
+
+			shards := storeShards(store)
+			var names []string
+			for _, shard := range shards {
+				names = append(names, shard.Name)
+			}
+			Expect(names).To(Equal([]string{
+				"store-shard-0",
+				"store-shard-1",
+				"store-shard-2",
+				"store-shard-3",
+			}))
+
+			dm := &appsv1.Deployment{}
+			Expect(setStoreDeployment(dm, nil, shards[3], store)).To(Succeed())
+
+			Expect(dm.Spec.Selector.MatchLabels).To(HaveKeyWithValue(storeShardLabel, "3"))
+			Expect(dm.Spec.Template.Labels).To(HaveKeyWithValue(storeShardLabel, "3"))
+			args := dm.Spec.Template.Spec.Containers[0].Args
+			Expect(args).To(ContainElement("--max-time=-2w"))
+			Expect(args).To(ContainElement(`--selector.relabel-config=- action: hashmod
+  modulus: 2
+  source_labels:
+  - __block_id
+  target_label: shard
+- action: keep
+  regex: "1"
+  source_labels:
+  - shard
+`))
+
+			service := &corev1.Service{}
+			makeStoreShardService(service, shards[3], store)
+			Expect(service.Spec.Selector).To(HaveKeyWithValue(storeShardLabel, "3"))
+		})
+
+		It("should refuse shards the image does not support", func() {
+			hashmodShards := int32(2)
+			image := "quay.io/thanos/thanos:v0.11.0"
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					Image:      &image,
+					Shards: &thanosv1beta1.StoreShards{
+						HashmodShards: &hashmodShards,
+					},
+				},
+			}
+
+			err := setStoreDeployment(&appsv1.Deployment{}, nil, storeShards(store)[0], store)
+			Expect(err).To(MatchError(ContainSubstring("v0.12.0")))
+			errs := validateStore(&store)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("spec.image"))
+		})
+	})
+
+	Context("setStoreStatefulSet", func() {
+		It("should keep the data dir on a persistent volume", func() {
+			storageClassName := "ssd"
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					Persistence: &thanosv1beta1.StorePersistence{
+						Enabled:          true,
+						Storage:          "10Gi",
+						StorageClassName: &storageClassName,
+					},
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+
+			Expect(setStoreStatefulSet(ss, nil, storeShards(store)[0], store)).To(Succeed())
+
+			Expect(ss.Spec.ServiceName).To(Equal("store-headless"))
+			Expect(ss.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			claim := ss.Spec.VolumeClaimTemplates[0]
+			Expect(claim.Spec.StorageClassName).To(Equal(&storageClassName))
+			storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+			Expect(storage.String()).To(Equal("10Gi"))
+			Expect(ss.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name:      claim.Name,
+				MountPath: storeDir,
+			}))
+		})
+
+		It("should leave the claim templates of existing statefulsets alone", func() {
+			store := thanosv1beta1.Store{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "store",
+					Namespace: "default",
+				},
+				Spec: thanosv1beta1.StoreSpec{
+					BucketName: "metrics",
+					Persistence: &thanosv1beta1.StorePersistence{
+						Enabled: true,
+						Storage: "20Gi",
+					},
+				},
+			}
+			ss := &appsv1.StatefulSet{}
+			ss.CreationTimestamp = metav1.Now()
+			ss.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{}}
+
+			Expect(setStoreStatefulSet(ss, nil, storeShards(store)[0], store)).To(Succeed())
+
+			Expect(ss.Spec.VolumeClaimTemplates).To(Equal([]corev1.PersistentVolumeClaim{{}}))
+		})
+
+		It("should default the size of the volumes", func() {
+			store := thanosv1beta1.Store{
+				Spec: thanosv1beta1.StoreSpec{
+					Persistence: &thanosv1beta1.StorePersistence{Enabled: true},
+				},
+			}
+
+			claim, err := makeStoreVolumeClaimTemplate(store)
+			Expect(err).NotTo(HaveOccurred())
+
+			storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+			Expect(storage.String()).To(Equal(storeStorage))
+			Expect(storePersistent(store)).To(BeTrue())
+
+			store.Spec.Persistence.Enabled = false
+			Expect(storePersistent(store)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,196 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// QuerierValidator rejects invalid Queriers
+type QuerierValidator struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-thanos-orangesys-io-v1beta1-querier,mutating=false,failurePolicy=fail,groups=thanos.orangesys.io,resources=queriers,verbs=create;update,versions=v1beta1,name=vquerier.thanos.orangesys.io
+
+// Handle admits the Querier of req if it is valid
+func (v *QuerierValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	querier := &thanosv1beta1.Querier{}
+	if err := v.decoder.Decode(req, querier); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	return validationResponse(validateQuerier(querier))
+}
+
+// InjectDecoder injects the decoder into a QuerierValidator.
+func (v *QuerierValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// StoreValidator rejects invalid Stores
+type StoreValidator struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-thanos-orangesys-io-v1beta1-store,mutating=false,failurePolicy=fail,groups=thanos.orangesys.io,resources=stores,verbs=create;update,versions=v1beta1,name=vstore.thanos.orangesys.io
+
+// Handle admits the Store of req if it is valid
+func (v *StoreValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	store, old := &thanosv1beta1.Store{}, &thanosv1beta1.Store{}
+	if err := decodeRequest(v.decoder, req, store, old); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	errs := validateStore(store)
+	if req.Operation == admissionv1beta1.Update {
+		errs = append(errs, validateStoreUpdate(store, old)...)
+	}
+	return validationResponse(errs)
+}
+
+// InjectDecoder injects the decoder into a StoreValidator.
+func (v *StoreValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// ReceiverValidator rejects invalid Receivers
+type ReceiverValidator struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-thanos-orangesys-io-v1beta1-receiver,mutating=false,failurePolicy=fail,groups=thanos.orangesys.io,resources=receivers,verbs=create;update,versions=v1beta1,name=vreceiver.thanos.orangesys.io
+
+// Handle admits the Receiver of req if it is valid
+func (v *ReceiverValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	receiver, old := &thanosv1beta1.Receiver{}, &thanosv1beta1.Receiver{}
+	if err := decodeRequest(v.decoder, req, receiver, old); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	errs := validateReceiver(receiver)
+	if req.Operation == admissionv1beta1.Update {
+		errs = append(errs, validateReceiverUpdate(receiver, old)...)
+	}
+	return validationResponse(errs)
+}
+
+// InjectDecoder injects the decoder into a ReceiverValidator.
+func (v *ReceiverValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// CompactorValidator rejects invalid Compactors
+type CompactorValidator struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-thanos-orangesys-io-v1beta1-compactor,mutating=false,failurePolicy=fail,groups=thanos.orangesys.io,resources=compactors,verbs=create;update,versions=v1beta1,name=vcompactor.thanos.orangesys.io
+
+// Handle admits the Compactor of req if it is valid
+func (v *CompactorValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	compactor := &thanosv1beta1.Compactor{}
+	if err := v.decoder.Decode(req, compactor); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	return validationResponse(validateCompactor(compactor))
+}
+
+// InjectDecoder injects the decoder into a CompactorValidator.
+func (v *CompactorValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// RulerValidator rejects invalid Rulers
+type RulerValidator struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-thanos-orangesys-io-v1beta1-ruler,mutating=false,failurePolicy=fail,groups=thanos.orangesys.io,resources=rulers,verbs=create;update,versions=v1beta1,name=vruler.thanos.orangesys.io
+
+// Handle admits the Ruler of req if it is valid
+func (v *RulerValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ruler := &thanosv1beta1.Ruler{}
+	if err := v.decoder.Decode(req, ruler); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	return validationResponse(validateRuler(ruler))
+}
+
+// InjectDecoder injects the decoder into a RulerValidator.
+func (v *RulerValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// SidecarValidator rejects invalid Sidecars
+type SidecarValidator struct {
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-thanos-orangesys-io-v1beta1-sidecar,mutating=false,failurePolicy=fail,groups=thanos.orangesys.io,resources=sidecars,verbs=create;update,versions=v1beta1,name=vsidecar.thanos.orangesys.io
+
+// Handle admits the Sidecar of req if it is valid
+func (v *SidecarValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	sidecar := &thanosv1beta1.Sidecar{}
+	if err := v.decoder.Decode(req, sidecar); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	return validationResponse(validateSidecar(sidecar))
+}
+
+// InjectDecoder injects the decoder into a SidecarValidator.
+func (v *SidecarValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// decodeRequest decodes the object of req into obj and, for updates,
+// the object being replaced into old.
+func decodeRequest(d *admission.Decoder, req admission.Request, obj, old runtime.Object) error {
+	if err := d.DecodeRaw(req.Object, obj); err != nil {
+		return err
+	}
+	if req.Operation == admissionv1beta1.Update {
+		return d.DecodeRaw(req.OldObject, old)
+	}
+	return nil
+}
+
+// validationResponse denies the request when errs is not empty
+func validationResponse(errs field.ErrorList) admission.Response {
+	if len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// newTestDecoder returns a decoder of the thanos resources
+func newTestDecoder() *admission.Decoder {
+	scheme := runtime.NewScheme()
+	Expect(thanosv1beta1.AddToScheme(scheme)).To(Succeed())
+	decoder, err := admission.NewDecoder(scheme)
+	Expect(err).NotTo(HaveOccurred())
+	return decoder
+}
+
+// typeMeta returns the apiVersion and kind of the thanos resource kind
+func typeMeta(kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{
+		APIVersion: thanosv1beta1.GroupVersion.String(),
+		Kind:       kind,
+	}
+}
+
+// admissionRequest returns the request of operation on obj, replacing old on
+// updates. obj and old carry their apiVersion and kind.
+func admissionRequest(operation admissionv1beta1.Operation, obj, old runtime.Object) admission.Request {
+	req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{Operation: operation}}
+	raw, err := json.Marshal(obj)
+	Expect(err).NotTo(HaveOccurred())
+	req.Object.Raw = raw
+	if old != nil {
+		raw, err := json.Marshal(old)
+		Expect(err).NotTo(HaveOccurred())
+		req.OldObject.Raw = raw
+	}
+	return req
+}
+
+var _ = Describe("Validating webhooks", func() {
+	image := "quay.io/thanos/thanos:v0.12.2"
+	bucket := &thanosv1beta1.ObjectStorage{
+		GCS: &thanosv1beta1.GCSObjectStorage{Bucket: "metrics"},
+	}
+
+	It("should admit valid queriers and deny invalid ones", func() {
+		validator := &QuerierValidator{}
+		Expect(validator.InjectDecoder(newTestDecoder())).To(Succeed())
+		querier := &thanosv1beta1.Querier{
+			TypeMeta: typeMeta("Querier"),
+			Spec:     thanosv1beta1.QuerierSpec{Image: &image},
+		}
+
+		resp := validator.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, querier, nil))
+		Expect(resp.Allowed).To(BeTrue())
+
+		querier.Spec.LogLevel = "verbose"
+		resp = validator.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, querier, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.logLevel"))
+	})
+
+	It("should deny store updates shrinking the volumes", func() {
+		validator := &StoreValidator{}
+		Expect(validator.InjectDecoder(newTestDecoder())).To(Succeed())
+		old := &thanosv1beta1.Store{
+			TypeMeta: typeMeta("Store"),
+			Spec: thanosv1beta1.StoreSpec{
+				Image:         &image,
+				ObjectStorage: bucket,
+				Persistence: &thanosv1beta1.StorePersistence{
+					Enabled: true,
+					Storage: "10Gi",
+				},
+			},
+		}
+		store := old.DeepCopy()
+		store.Spec.Persistence.Storage = "5Gi"
+
+		resp := validator.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, store, nil))
+		Expect(resp.Allowed).To(BeTrue())
+
+		resp = validator.Handle(context.Background(), admissionRequest(admissionv1beta1.Update, store, old))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.persistence.storage"))
+
+		resp = validator.Handle(context.Background(), admissionRequest(admissionv1beta1.Update, old, store))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+
+	It("should deny receivers routing tenants to missing replicas", func() {
+		validator := &ReceiverValidator{}
+		Expect(validator.InjectDecoder(newTestDecoder())).To(Succeed())
+		receiver := &thanosv1beta1.Receiver{
+			TypeMeta: typeMeta("Receiver"),
+			Spec: thanosv1beta1.ReceiverSpec{
+				Image:         &image,
+				ObjectStorage: bucket,
+				Tenants: []thanosv1beta1.ReceiverTenant{
+					{Name: "team-a", Replicas: []int32{1}},
+				},
+			},
+		}
+
+		resp := validator.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, receiver, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.tenants[0].replicas[0]"))
+	})
+
+	It("should validate compactors and rulers", func() {
+		compactorValidator := &CompactorValidator{}
+		Expect(compactorValidator.InjectDecoder(newTestDecoder())).To(Succeed())
+		compactor := &thanosv1beta1.Compactor{
+			TypeMeta: typeMeta("Compactor"),
+			Spec: thanosv1beta1.CompactorSpec{
+				ObjectStorage:          bucket,
+				RetentionResolutionRaw: "30d",
+			},
+		}
+
+		resp := compactorValidator.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, compactor, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.image"))
+
+		rulerValidator := &RulerValidator{}
+		Expect(rulerValidator.InjectDecoder(newTestDecoder())).To(Succeed())
+		ruler := &thanosv1beta1.Ruler{
+			TypeMeta: typeMeta("Ruler"),
+			Spec: thanosv1beta1.RulerSpec{
+				Image:              &image,
+				ObjectStorage:      bucket,
+				EvaluationInterval: "30s",
+			},
+		}
+
+		resp = rulerValidator.Handle(context.Background(), admissionRequest(admissionv1beta1.Create, ruler, nil))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,516 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+var (
+	// durationRegexp matches the durations accepted by thanos flags
+	durationRegexp = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d|w|y)$`)
+	// bytesRegexp matches the byte sizes accepted by thanos flags
+	bytesRegexp = regexp.MustCompile(`^[0-9]+(B|KB|MB|GB|TB|PB|KiB|MiB|GiB|TiB|PiB)$`)
+	// relativeTimeRegexp matches the times relative to now accepted by thanos flags
+	relativeTimeRegexp = regexp.MustCompile(`^-?[0-9]+(ms|s|m|h|d|w|y)$`)
+	// labelNameRegexp matches valid Prometheus label names
+	labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	logLevels       = []string{"debug", "info", "warn", "error"}
+	// timeUnits are the lengths of the units of relativeTimeRegexp
+	timeUnits = map[string]time.Duration{
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+		"y":  365 * 24 * time.Hour,
+	}
+)
+
+func validateImage(image *string, fldPath *field.Path) field.ErrorList {
+	if image == nil || *image == "" {
+		return field.ErrorList{field.Required(fldPath, "image is required")}
+	}
+	return nil
+}
+
+func validateLogLevel(level string, fldPath *field.Path) field.ErrorList {
+	if level == "" {
+		return nil
+	}
+	for _, l := range logLevels {
+		if level == l {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(fldPath, level, logLevels)}
+}
+
+func validateDuration(value string, fldPath *field.Path) field.ErrorList {
+	if value == "" || durationRegexp.MatchString(value) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath, value, "must match "+durationRegexp.String())}
+}
+
+// validateTime accepts the RFC3339 times and the durations relative to now
+// understood by the --min-time and --max-time flags of thanos.
+func validateTime(value string, fldPath *field.Path) field.ErrorList {
+	if value == "" || relativeTimeRegexp.MatchString(value) {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, value, "must be an RFC3339 time or match "+relativeTimeRegexp.String())}
+	}
+	return nil
+}
+
+// resolveTime returns the time of a value accepted by validateTime, resolving
+// the durations relative to now. It returns nil for empty values.
+func resolveTime(value string, now time.Time) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if m := relativeTimeRegexp.FindStringSubmatch(value); m != nil {
+		n, err := strconv.ParseInt(strings.TrimSuffix(value, m[1]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		t := now.Add(time.Duration(n) * timeUnits[m[1]])
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// timeRange is the range between min and max, unbounded where they are nil
+type timeRange struct {
+	min, max *time.Time
+}
+
+// overlaps reports whether r and o share a time
+func (r timeRange) overlaps(o timeRange) bool {
+	return (r.min == nil || o.max == nil || r.min.Before(*o.max)) &&
+		(o.min == nil || r.max == nil || o.min.Before(*r.max))
+}
+
+func validateBytes(value string, fldPath *field.Path) field.ErrorList {
+	if value == "" || bytesRegexp.MatchString(value) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath, value, "must match "+bytesRegexp.String())}
+}
+
+func validateQuantity(value string, fldPath *field.Path) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	if _, err := resource.ParseQuantity(value); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, value, err.Error())}
+	}
+	return nil
+}
+
+// validateProbes rejects probe settings the API server would refuse
+func validateProbes(probes *thanosv1beta1.Probes, fldPath *field.Path) field.ErrorList {
+	if probes == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	errs = append(errs, validateProbeSettings(probes.Liveness, fldPath.Child("liveness"))...)
+	errs = append(errs, validateProbeSettings(probes.Readiness, fldPath.Child("readiness"))...)
+	return errs
+}
+
+func validateProbeSettings(settings *thanosv1beta1.ProbeSettings, fldPath *field.Path) field.ErrorList {
+	if settings == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if v := settings.InitialDelaySeconds; v != nil && *v < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("initialDelaySeconds"), *v, "must not be negative"))
+	}
+	positive := []struct {
+		name  string
+		value *int32
+	}{
+		{"timeoutSeconds", settings.TimeoutSeconds},
+		{"periodSeconds", settings.PeriodSeconds},
+		{"successThreshold", settings.SuccessThreshold},
+		{"failureThreshold", settings.FailureThreshold},
+	}
+	for _, p := range positive {
+		if p.value != nil && *p.value < 1 {
+			errs = append(errs, field.Invalid(fldPath.Child(p.name), *p.value, "must be at least 1"))
+		}
+	}
+	return errs
+}
+
+// validateLabelNames rejects names Prometheus does not accept as label
+// names, including those reserved for internal use.
+func validateLabelNames(labels map[string]string, fldPath *field.Path) field.ErrorList {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs field.ErrorList
+	for _, name := range names {
+		switch {
+		case !labelNameRegexp.MatchString(name):
+			errs = append(errs, field.Invalid(fldPath.Key(name), name, "must match "+labelNameRegexp.String()))
+		case strings.HasPrefix(name, "__"):
+			errs = append(errs, field.Invalid(fldPath.Key(name), name, "names starting with __ are reserved"))
+		}
+	}
+	return errs
+}
+
+func validateLabelSelector(selector *metav1.LabelSelector, fldPath *field.Path) field.ErrorList {
+	if selector == nil {
+		return nil
+	}
+	if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, selector, err.Error())}
+	}
+	return nil
+}
+
+// validateObjstore requires the component whose spec is at fldPath to be
+// given a bucket, by objectStorageConfig, objectStorage or the deprecated
+// GCS-only bucketName and objstoreType
+func validateObjstore(
+	config *corev1.SecretKeySelector,
+	storage *thanosv1beta1.ObjectStorage,
+	objstoreType string,
+	bucketName string,
+	fldPath *field.Path,
+) field.ErrorList {
+	if config != nil || storage != nil {
+		return validateObjectStorage(storage, fldPath.Child("objectStorage"))
+	}
+	if bucketName == "" {
+		return field.ErrorList{field.Required(fldPath.Child("objectStorage"), "one of objectStorage, objectStorageConfig or bucketName is required")}
+	}
+	if objstoreType != "" && objstoreType != "GCS" {
+		return field.ErrorList{field.Invalid(fldPath.Child("objstoreType"), objstoreType, "only GCS is supported, configure the other providers with objectStorage")}
+	}
+	return nil
+}
+
+func validateObjectStorage(storage *thanosv1beta1.ObjectStorage, fldPath *field.Path) field.ErrorList {
+	if storage == nil {
+		return nil
+	}
+	providers := 0
+	var errs field.ErrorList
+	if storage.GCS != nil {
+		providers++
+		if storage.GCS.Bucket == "" {
+			errs = append(errs, field.Required(fldPath.Child("gcs", "bucket"), ""))
+		}
+	}
+	if storage.S3 != nil {
+		providers++
+		if storage.S3.Bucket == "" {
+			errs = append(errs, field.Required(fldPath.Child("s3", "bucket"), ""))
+		}
+		if storage.S3.Endpoint == "" {
+			errs = append(errs, field.Required(fldPath.Child("s3", "endpoint"), ""))
+		}
+	}
+	if storage.Azure != nil {
+		providers++
+		if storage.Azure.StorageAccount == "" {
+			errs = append(errs, field.Required(fldPath.Child("azure", "storageAccount"), ""))
+		}
+		if storage.Azure.StorageAccountKey == nil {
+			errs = append(errs, field.Required(fldPath.Child("azure", "storageAccountKey"), ""))
+		}
+		if storage.Azure.Container == "" {
+			errs = append(errs, field.Required(fldPath.Child("azure", "container"), ""))
+		}
+	}
+	if storage.Filesystem != nil {
+		providers++
+		if storage.Filesystem.Directory == "" {
+			errs = append(errs, field.Required(fldPath.Child("filesystem", "directory"), ""))
+		}
+	}
+	if providers != 1 {
+		errs = append(errs, field.Invalid(fldPath, storage, "exactly one of gcs, s3, azure or filesystem must be set"))
+	}
+	return errs
+}
+
+// validateQuerier returns the problems found in t
+func validateQuerier(t *thanosv1beta1.Querier) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+	errs = append(errs, validateImage(t.Spec.Image, spec.Child("image"))...)
+	errs = append(errs, validateLogLevel(t.Spec.LogLevel, spec.Child("logLevel"))...)
+	errs = append(errs, validateProbes(t.Spec.Probes, spec.Child("probes"))...)
+	errs = append(errs, validateLabelSelector(t.Spec.StoreSelector, spec.Child("storeSelector"))...)
+	for i, store := range t.Spec.Stores {
+		if store == "" {
+			errs = append(errs, field.Required(spec.Child("stores").Index(i), ""))
+		}
+	}
+	return errs
+}
+
+// validateStore returns the problems found in t
+func validateStore(t *thanosv1beta1.Store) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+	errs = append(errs, validateImage(t.Spec.Image, spec.Child("image"))...)
+	errs = append(errs, validateLogLevel(t.Spec.LogLevel, spec.Child("logLevel"))...)
+	errs = append(errs, validateProbes(t.Spec.Probes, spec.Child("probes"))...)
+	errs = append(errs, validateBytes(t.Spec.IndexCacheSize, spec.Child("indexCacheSize"))...)
+	errs = append(errs, validateBytes(t.Spec.ChunkPoolSize, spec.Child("chunkPoolSize"))...)
+	errs = append(errs, validateObjstore(t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage, t.Spec.ObjectStorageType, t.Spec.BucketName, spec)...)
+	errs = append(errs, validateTime(t.Spec.MinTime, spec.Child("minTime"))...)
+	errs = append(errs, validateTime(t.Spec.MaxTime, spec.Child("maxTime"))...)
+	errs = append(errs, validateStoreShards(t.Spec.Shards, spec.Child("shards"))...)
+	if min := storeShardsVersion(*t); min != nil && t.Spec.Image != nil && !imageAtLeast(*t.Spec.Image, min) {
+		errs = append(errs, field.Invalid(spec.Child("image"), *t.Spec.Image, "minTime, maxTime and shards require thanos v"+min.String()+" or later"))
+	}
+	if t.Spec.Persistence != nil {
+		errs = append(errs, validateQuantity(t.Spec.Persistence.Storage, spec.Child("persistence", "storage"))...)
+	}
+	return errs
+}
+
+// validateStoreUpdate returns the problems found in changing old into t
+func validateStoreUpdate(t, old *thanosv1beta1.Store) field.ErrorList {
+	if !storePersistent(*t) || !storePersistent(*old) {
+		return nil
+	}
+	spec := field.NewPath("spec")
+	// invalid storage sizes are reported by validateStore
+	claim, err := makeStoreVolumeClaimTemplate(*t)
+	if err != nil {
+		return nil
+	}
+	oldClaim, err := makeStoreVolumeClaimTemplate(*old)
+	if err != nil {
+		return nil
+	}
+	storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	oldStorage := oldClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+	if storage.Cmp(oldStorage) < 0 {
+		return field.ErrorList{field.Forbidden(spec.Child("persistence", "storage"), "volumes can not shrink from "+oldStorage.String())}
+	}
+	return nil
+}
+
+func validateStoreShards(shards *thanosv1beta1.StoreShards, fldPath *field.Path) field.ErrorList {
+	if shards == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if shards.HashmodShards != nil && *shards.HashmodShards < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("hashmodShards"), *shards.HashmodShards, "must be at least 1"))
+	}
+	// the partitions must not overlap, or the blocks in common would be
+	// served twice
+	now := time.Now()
+	ranges := map[int]timeRange{}
+	for i, partition := range shards.TimePartitions {
+		partitionPath := fldPath.Child("timePartitions").Index(i)
+		timeErrs := append(
+			validateTime(partition.MinTime, partitionPath.Child("minTime")),
+			validateTime(partition.MaxTime, partitionPath.Child("maxTime"))...,
+		)
+		if len(timeErrs) > 0 {
+			errs = append(errs, timeErrs...)
+			continue
+		}
+		// valid times always resolve
+		min, _ := resolveTime(partition.MinTime, now)
+		max, _ := resolveTime(partition.MaxTime, now)
+		if min != nil && max != nil && !min.Before(*max) {
+			errs = append(errs, field.Invalid(partitionPath.Child("maxTime"), partition.MaxTime, "must be after minTime"))
+			continue
+		}
+		r := timeRange{min: min, max: max}
+		for j := 0; j < i; j++ {
+			if other, ok := ranges[j]; ok && r.overlaps(other) {
+				errs = append(errs, field.Invalid(partitionPath, partition, fmt.Sprintf("overlaps timePartitions[%d]", j)))
+				break
+			}
+		}
+		ranges[i] = r
+	}
+	return errs
+}
+
+// validateCompactor returns the problems found in t
+func validateCompactor(t *thanosv1beta1.Compactor) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+	errs = append(errs, validateImage(t.Spec.Image, spec.Child("image"))...)
+	errs = append(errs, validateLogLevel(t.Spec.LogLevel, spec.Child("logLevel"))...)
+	errs = append(errs, validateProbes(t.Spec.Probes, spec.Child("probes"))...)
+	errs = append(errs, validateDuration(t.Spec.RetentionResolutionRaw, spec.Child("retentionResolutionRaw"))...)
+	errs = append(errs, validateDuration(t.Spec.RetentionResolution5m, spec.Child("retentionResolution5m"))...)
+	errs = append(errs, validateDuration(t.Spec.RetentionResolution1h, spec.Child("retentionResolution1h"))...)
+	errs = append(errs, validateQuantity(t.Spec.Storage, spec.Child("storage"))...)
+	errs = append(errs, validateObjstore(t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage, t.Spec.ObjectStorageType, t.Spec.BucketName, spec)...)
+	return errs
+}
+
+// validateSidecar returns the problems found in t
+func validateSidecar(t *thanosv1beta1.Sidecar) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+	errs = append(errs, validateImage(t.Spec.Image, spec.Child("image"))...)
+	errs = append(errs, validateLogLevel(t.Spec.LogLevel, spec.Child("logLevel"))...)
+	errs = append(errs, validateProbes(t.Spec.Probes, spec.Child("probes"))...)
+	if t.Spec.Selector == nil {
+		errs = append(errs, field.Required(spec.Child("selector"), "selector is required"))
+	}
+	errs = append(errs, validateLabelSelector(t.Spec.Selector, spec.Child("selector"))...)
+	if t.Spec.TSDBVolumeName == "" {
+		errs = append(errs, field.Required(spec.Child("tsdbVolumeName"), "tsdbVolumeName is required"))
+	}
+	errs = append(errs, validateObjstore(t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage, t.Spec.ObjectStorageType, t.Spec.BucketName, spec)...)
+	return errs
+}
+
+// validateRuler returns the problems found in t
+func validateRuler(t *thanosv1beta1.Ruler) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+	errs = append(errs, validateImage(t.Spec.Image, spec.Child("image"))...)
+	errs = append(errs, validateLogLevel(t.Spec.LogLevel, spec.Child("logLevel"))...)
+	errs = append(errs, validateProbes(t.Spec.Probes, spec.Child("probes"))...)
+	errs = append(errs, validateDuration(t.Spec.Retention, spec.Child("retention"))...)
+	errs = append(errs, validateDuration(t.Spec.EvaluationInterval, spec.Child("evaluationInterval"))...)
+	errs = append(errs, validateQuantity(t.Spec.Storage, spec.Child("storage"))...)
+	errs = append(errs, validateLabelSelector(t.Spec.RuleSelector, spec.Child("ruleSelector"))...)
+	errs = append(errs, validateObjstore(t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage, t.Spec.ObjectStorageType, t.Spec.BucketName, spec)...)
+	if t.Spec.QuerierRef != nil && t.Spec.QuerierRef.Name == "" {
+		errs = append(errs, field.Required(spec.Child("querierRef", "name"), ""))
+	}
+	for i, url := range t.Spec.AlertmanagersURLs {
+		if url == "" {
+			errs = append(errs, field.Required(spec.Child("alertmanagersURLs").Index(i), ""))
+		}
+	}
+	return errs
+}
+
+// validateReceiver returns the problems found in t
+func validateReceiver(t *thanosv1beta1.Receiver) field.ErrorList {
+	spec := field.NewPath("spec")
+	var errs field.ErrorList
+	errs = append(errs, validateImage(t.Spec.Image, spec.Child("image"))...)
+	errs = append(errs, validateLogLevel(t.Spec.LogLevel, spec.Child("logLevel"))...)
+	errs = append(errs, validateProbes(t.Spec.Probes, spec.Child("probes"))...)
+	errs = append(errs, validateDuration(t.Spec.Retention, spec.Child("retention"))...)
+	errs = append(errs, validateQuantity(t.Spec.Storage, spec.Child("storage"))...)
+	errs = append(errs, validateLabelNames(t.Spec.ExternalLabels, spec.Child("externalLabels"))...)
+	errs = append(errs, validateObjstore(t.Spec.ObjectStorageConfig, t.Spec.ObjectStorage, t.Spec.ObjectStorageType, t.Spec.BucketName, spec)...)
+	// every write needs a quorum of the replicationFactor replicas
+	// holding its series
+	switch replicationFactor := receiverReplicationFactor(*t); {
+	case replicationFactor < 1:
+		errs = append(errs, field.Invalid(spec.Child("replicationFactor"), replicationFactor, "must be at least 1"))
+	case replicationFactor > receiverReplicas(*t):
+		errs = append(errs, field.Invalid(spec.Child("replicationFactor"), replicationFactor, fmt.Sprintf("must not exceed spec.replicas (%d)", receiverReplicas(*t))))
+	}
+	errs = append(errs, validateTenants(*t, spec.Child("tenants"))...)
+	return errs
+}
+
+// validateTenants returns the problems found in the tenants of t: each needs a
+// unique name and at least replicationFactor ordinals of existing receive
+// replicas
+func validateTenants(t thanosv1beta1.Receiver, path *field.Path) field.ErrorList {
+	replicas := receiverReplicas(t)
+	replicationFactor := receiverReplicationFactor(t)
+	var errs field.ErrorList
+	names := map[string]bool{}
+	for i, tenant := range t.Spec.Tenants {
+		name := path.Index(i).Child("name")
+		switch {
+		case tenant.Name == "":
+			errs = append(errs, field.Required(name, ""))
+		case names[tenant.Name]:
+			errs = append(errs, field.Duplicate(name, tenant.Name))
+		}
+		names[tenant.Name] = true
+
+		switch {
+		case len(tenant.Replicas) == 0:
+			errs = append(errs, field.Required(path.Index(i).Child("replicas"), ""))
+		case int32(len(tenant.Replicas)) < replicationFactor:
+			errs = append(errs, field.Invalid(path.Index(i).Child("replicas"), tenant.Replicas, fmt.Sprintf("must list at least replicationFactor (%d) replicas", replicationFactor)))
+		}
+		ordinals := map[int32]bool{}
+		for j, ordinal := range tenant.Replicas {
+			ordinalPath := path.Index(i).Child("replicas").Index(j)
+			switch {
+			case ordinal < 0 || ordinal >= replicas:
+				errs = append(errs, field.Invalid(ordinalPath, ordinal, fmt.Sprintf("must be between 0 and %d", replicas-1)))
+			case ordinals[ordinal]:
+				errs = append(errs, field.Duplicate(ordinalPath, ordinal))
+			}
+			ordinals[ordinal] = true
+		}
+	}
+	return errs
+}
+
+// validateReceiverUpdate returns the problems found in changing old into t
+func validateReceiverUpdate(t, old *thanosv1beta1.Receiver) field.ErrorList {
+	spec := field.NewPath("spec")
+	// invalid storage sizes are reported by validateReceiver
+	claim, err := makeReceiverVolumeClaimTemplate(*t)
+	if err != nil {
+		return nil
+	}
+	oldClaim, err := makeReceiverVolumeClaimTemplate(*old)
+	if err != nil {
+		return nil
+	}
+	storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	oldStorage := oldClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+	if storage.Cmp(oldStorage) < 0 {
+		return field.ErrorList{field.Forbidden(spec.Child("storage"), "volumes can not shrink from "+oldStorage.String())}
+	}
+	return nil
+}
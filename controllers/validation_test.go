@@ -0,0 +1,251 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
+)
+
+// errorFields returns the fields of errs
+func errorFields(errs field.ErrorList) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+var _ = Describe("Validation", func() {
+	image := "quay.io/thanos/thanos:v0.12.2"
+	bucket := func() *thanosv1beta1.ObjectStorage {
+		return &thanosv1beta1.ObjectStorage{
+			GCS: &thanosv1beta1.GCSObjectStorage{Bucket: "metrics"},
+		}
+	}
+
+	Context("validateQuerier", func() {
+		It("should reject empty stores and unknown log levels", func() {
+			querier := &thanosv1beta1.Querier{
+				Spec: thanosv1beta1.QuerierSpec{
+					Image:    &image,
+					LogLevel: "verbose",
+					Stores:   []string{"prometheus:10901", ""},
+				},
+			}
+
+			Expect(errorFields(validateQuerier(querier))).To(ConsistOf("spec.logLevel", "spec.stores[1]"))
+		})
+	})
+
+	Context("validateStore", func() {
+		It("should accept a complete store", func() {
+			store := &thanosv1beta1.Store{
+				Spec: thanosv1beta1.StoreSpec{
+					Image:          &image,
+					ObjectStorage:  bucket(),
+					IndexCacheSize: "250MB",
+					MinTime:        "-2w",
+					MaxTime:        "2019-11-20T00:00:00Z",
+				},
+			}
+
+			Expect(validateStore(store)).To(BeEmpty())
+		})
+
+		It("should reject malformed sizes and times", func() {
+			store := &thanosv1beta1.Store{
+				Spec: thanosv1beta1.StoreSpec{
+					Image:          &image,
+					ObjectStorage:  bucket(),
+					IndexCacheSize: "250 megabytes",
+					MinTime:        "yesterday",
+					Persistence: &thanosv1beta1.StorePersistence{
+						Enabled: true,
+						Storage: "ten gigs",
+					},
+				},
+			}
+
+			Expect(errorFields(validateStore(store))).To(ConsistOf(
+				"spec.indexCacheSize",
+				"spec.minTime",
+				"spec.persistence.storage",
+			))
+		})
+
+		It("should reject empty and overlapping time partitions", func() {
+			store := &thanosv1beta1.Store{
+				Spec: thanosv1beta1.StoreSpec{
+					Image:         &image,
+					ObjectStorage: bucket(),
+					Shards: &thanosv1beta1.StoreShards{
+						TimePartitions: []thanosv1beta1.StoreTimePartition{
+							{MinTime: "-2w"},
+							{MinTime: "-4w", MaxTime: "-2w"},
+							{MinTime: "-6w", MaxTime: "-3w"},
+							{MinTime: "-6w", MaxTime: "-8w"},
+							{MaxTime: "-6w"},
+						},
+					},
+				},
+			}
+
+			Expect(errorFields(validateStore(store))).To(ConsistOf(
+				"spec.shards.timePartitions[2]",
+				"spec.shards.timePartitions[3].maxTime",
+			))
+		})
+
+		It("should forbid shrinking the volumes", func() {
+			persistence := func(storage string) thanosv1beta1.StoreSpec {
+				return thanosv1beta1.StoreSpec{
+					Persistence: &thanosv1beta1.StorePersistence{Enabled: true, Storage: storage},
+				}
+			}
+			old := &thanosv1beta1.Store{Spec: persistence("10Gi")}
+			store := &thanosv1beta1.Store{Spec: persistence("5Gi")}
+
+			Expect(errorFields(validateStoreUpdate(store, old))).To(ConsistOf("spec.persistence.storage"))
+			Expect(validateStoreUpdate(old, store)).To(BeEmpty())
+
+			// the volumes are dropped along with persistence
+			store.Spec.Persistence.Enabled = false
+			Expect(validateStoreUpdate(store, old)).To(BeEmpty())
+		})
+	})
+
+	Context("validateObjectStorage", func() {
+		It("should require exactly one provider", func() {
+			path := field.NewPath("spec", "objectStorage")
+
+			Expect(validateObjectStorage(bucket(), path)).To(BeEmpty())
+			Expect(errorFields(validateObjectStorage(&thanosv1beta1.ObjectStorage{}, path))).To(ConsistOf("spec.objectStorage"))
+
+			storage := bucket()
+			storage.Filesystem = &thanosv1beta1.FilesystemObjectStorage{Directory: "/data"}
+			Expect(errorFields(validateObjectStorage(storage, path))).To(ConsistOf("spec.objectStorage"))
+		})
+
+		It("should require the fields of the provider", func() {
+			storage := &thanosv1beta1.ObjectStorage{
+				Azure: &thanosv1beta1.AzureObjectStorage{StorageAccount: "metrics"},
+			}
+
+			Expect(errorFields(validateObjectStorage(storage, field.NewPath("spec", "objectStorage")))).To(ConsistOf(
+				"spec.objectStorage.azure.storageAccountKey",
+				"spec.objectStorage.azure.container",
+			))
+		})
+	})
+
+	Context("validateCompactor", func() {
+		It("should reject malformed retentions", func() {
+			compactor := &thanosv1beta1.Compactor{
+				Spec: thanosv1beta1.CompactorSpec{
+					Image:                  &image,
+					ObjectStorage:          bucket(),
+					RetentionResolutionRaw: "30d",
+					RetentionResolution5m:  "a month",
+				},
+			}
+
+			Expect(errorFields(validateCompactor(compactor))).To(ConsistOf("spec.retentionResolution5m"))
+		})
+
+		It("should require an image", func() {
+			compactor := &thanosv1beta1.Compactor{
+				Spec: thanosv1beta1.CompactorSpec{
+					ObjectStorage: bucket(),
+				},
+			}
+
+			Expect(errorFields(validateCompactor(compactor))).To(ConsistOf("spec.image"))
+		})
+	})
+
+	Context("validateRuler", func() {
+		It("should reject malformed intervals and selectors", func() {
+			ruler := &thanosv1beta1.Ruler{
+				Spec: thanosv1beta1.RulerSpec{
+					Image:              &image,
+					ObjectStorage:      bucket(),
+					EvaluationInterval: "30 seconds",
+					QuerierRef:         &corev1.LocalObjectReference{},
+					RuleSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "team",
+							Operator: "Near",
+						}},
+					},
+					AlertmanagersURLs: []string{"http://alertmanager:9093", ""},
+				},
+			}
+
+			Expect(errorFields(validateRuler(ruler))).To(ConsistOf(
+				"spec.evaluationInterval",
+				"spec.ruleSelector",
+				"spec.querierRef.name",
+				"spec.alertmanagersURLs[1]",
+			))
+		})
+	})
+
+	Context("validateReceiver", func() {
+		It("should reject replication factors above the replicas", func() {
+			replicas, replicationFactor := int32(1), int32(3)
+			receiver := &thanosv1beta1.Receiver{
+				Spec: thanosv1beta1.ReceiverSpec{
+					Image:             &image,
+					ObjectStorage:     bucket(),
+					Replicas:          &replicas,
+					ReplicationFactor: &replicationFactor,
+				},
+			}
+
+			Expect(errorFields(validateReceiver(receiver))).To(ConsistOf("spec.replicationFactor"))
+
+			replicas = 3
+			Expect(validateReceiver(receiver)).To(BeEmpty())
+		})
+	})
+
+	Context("validateSidecar", func() {
+		It("should require a selector and the TSDB volume", func() {
+			sidecar := &thanosv1beta1.Sidecar{
+				Spec: thanosv1beta1.SidecarSpec{
+					Image:         &image,
+					ObjectStorage: bucket(),
+				},
+			}
+
+			Expect(errorFields(validateSidecar(sidecar))).To(ConsistOf("spec.selector", "spec.tsdbVolumeName"))
+
+			sidecar.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "prometheus"},
+			}
+			sidecar.Spec.TSDBVolumeName = "prometheus-db"
+			Expect(validateSidecar(sidecar)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,44 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// imageVersion returns the thanos release an image is tagged with, nil when
+// the tag is not a release such as a commit build.
+func imageVersion(image string) *version.Version {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return nil
+	}
+	v, err := version.ParseSemantic(image[i+1:])
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// imageAtLeast tells whether image runs release min or a later one. Images
+// not tagged with a release are assumed to be recent enough.
+func imageAtLeast(image string, min *version.Version) bool {
+	v := imageVersion(image)
+	return v == nil || v.AtLeast(min)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 Gavin Zhou.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// volumeResizeRequeueAfter is how often the progress of a volume expansion
+// is checked, resized claims not being watched.
+const volumeResizeRequeueAfter = 30 * time.Second
+
+// makeVolumeClaimTemplate returns the template of the claims of storage
+// holding the data dir of a StatefulSet
+func makeVolumeClaimTemplate(storage string) (corev1.PersistentVolumeClaim, error) {
+	claim := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "thanos-persistent-storage"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		},
+	}
+	quantity, err := resource.ParseQuantity(storage)
+	if err != nil {
+		return claim, fmt.Errorf("invalid storage %q: %v", storage, err)
+	}
+	claim.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: quantity,
+	}
+	return claim, nil
+}
+
+// setVolumeClaimTemplate sets claim as the claim template of ss. The claim
+// templates are immutable, existing StatefulSets keep the ones they were
+// created with and their volumes are expanded by resizeVolumeClaims instead.
+func setVolumeClaimTemplate(ss *appsv1.StatefulSet, claim corev1.PersistentVolumeClaim) {
+	if ss.CreationTimestamp.IsZero() {
+		ss.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{claim}
+	}
+}
+
+// resizeVolumeClaims grows the claims ss created from the template named
+// after claim to the storage claim requests. Claims are never shrunk. It
+// returns how many of the bound claims have yet to reach that size.
+func resizeVolumeClaims(
+	ctx context.Context,
+	c client.Client,
+	ss *appsv1.StatefulSet,
+	claim corev1.PersistentVolumeClaim,
+) (pending, total int, err error) {
+	storage := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+
+	claims := &corev1.PersistentVolumeClaimList{}
+	err = c.List(ctx, claims,
+		client.InNamespace(ss.Namespace),
+		client.MatchingLabels(ss.Spec.Selector.MatchLabels),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	prefix := claim.Name + "-" + ss.Name + "-"
+	for i := range claims.Items {
+		pvc := &claims.Items[i]
+		if !strings.HasPrefix(pvc.Name, prefix) || pvc.Status.Phase != corev1.ClaimBound {
+			continue
+		}
+		total++
+
+		requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if requested.Cmp(storage) < 0 {
+			if pvc.Spec.Resources.Requests == nil {
+				pvc.Spec.Resources.Requests = corev1.ResourceList{}
+			}
+			pvc.Spec.Resources.Requests[corev1.ResourceStorage] = storage
+			if err := c.Update(ctx, pvc); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+		if capacity.Cmp(storage) < 0 {
+			pending++
+		}
+	}
+	return pending, total, nil
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
 
 	thanosv1beta1 "github.com/orangesys/thanos-operator/api/v1beta1"
 	"github.com/orangesys/thanos-operator/controllers"
@@ -29,6 +30,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -48,11 +50,31 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var webhookCertDir string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "The directory holding the tls.crt and tls.key serving the admission webhooks, which are served when it does.")
+	defaultResources := map[string]*string{
+		"querier":   flag.String("querier-default-resources", "cpu=100m,memory=1Gi", "Requests of querier containers which set none, as name=quantity pairs."),
+		"store":     flag.String("store-default-resources", "cpu=100m,memory=1Gi", "Requests of store containers which set none, as name=quantity pairs."),
+		"receiver":  flag.String("receiver-default-resources", "cpu=100m,memory=1Gi", "Requests of receiver containers which set none, as name=quantity pairs."),
+		"compactor": flag.String("compactor-default-resources", "cpu=100m,memory=1Gi", "Requests of compactor containers which set none, as name=quantity pairs."),
+		"ruler":     flag.String("ruler-default-resources", "cpu=100m,memory=1Gi", "Requests of ruler containers which set none, as name=quantity pairs."),
+		"sidecar":   flag.String("sidecar-default-resources", "cpu=100m,memory=128Mi", "Requests of injected sidecar containers which set none, as name=quantity pairs."),
+	}
 	flag.Parse()
 
 	ctrl.SetLogger(zap.Logger(true))
 
+	resources := map[string]corev1.ResourceList{}
+	for kind, value := range defaultResources {
+		list, err := controllers.ParseResourceList(*value)
+		if err != nil {
+			setupLog.Error(err, "invalid default resources", "flag", kind+"-default-resources")
+			os.Exit(1)
+		}
+		resources[kind] = list
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{Scheme: scheme, MetricsBindAddress: metricsAddr})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -60,35 +82,120 @@ func main() {
 	}
 
 	err = (&controllers.ReceiverReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("Receiver"),
-		Recorder: mgr.GetEventRecorderFor("receiver"),
-		Scheme:   mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Receiver"),
+		Recorder:         mgr.GetEventRecorderFor("receiver"),
+		Scheme:           mgr.GetScheme(),
+		DefaultResources: resources["receiver"],
 	}).SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Receiver")
 		os.Exit(1)
 	}
 	err = (&controllers.QuerierReconciler{
-		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("Querier"),
-		Recorder: mgr.GetEventRecorderFor("querier"),
-		Scheme:   mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Querier"),
+		Recorder:         mgr.GetEventRecorderFor("querier"),
+		Scheme:           mgr.GetScheme(),
+		DefaultResources: resources["querier"],
 	}).SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Querier")
 		os.Exit(1)
 	}
 	err = (&controllers.StoreReconciler{
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Store"),
+		Recorder:         mgr.GetEventRecorderFor("store"),
+		Scheme:           mgr.GetScheme(),
+		DefaultResources: resources["store"],
+	}).SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Store")
+		os.Exit(1)
+	}
+	err = (&controllers.CompactorReconciler{
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Compactor"),
+		Recorder:         mgr.GetEventRecorderFor("compactor"),
+		Scheme:           mgr.GetScheme(),
+		DefaultResources: resources["compactor"],
+	}).SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Compactor")
+		os.Exit(1)
+	}
+	err = (&controllers.RulerReconciler{
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Ruler"),
+		Recorder:         mgr.GetEventRecorderFor("ruler"),
+		Scheme:           mgr.GetScheme(),
+		DefaultResources: resources["ruler"],
+	}).SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Ruler")
+		os.Exit(1)
+	}
+	err = (&controllers.SidecarReconciler{
 		Client:   mgr.GetClient(),
-		Log:      ctrl.Log.WithName("controllers").WithName("Store"),
-		Recorder: mgr.GetEventRecorderFor("store"),
+		Log:      ctrl.Log.WithName("controllers").WithName("Sidecar"),
+		Recorder: mgr.GetEventRecorderFor("sidecar"),
 		Scheme:   mgr.GetScheme(),
 	}).SetupWithManager(mgr)
 	if err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Store")
+		setupLog.Error(err, "unable to create controller", "controller", "Sidecar")
 		os.Exit(1)
 	}
+	// Serve the webhooks whenever their serving certificate is mounted, as
+	// the webhook configurations deployed with it fail closed
+	if _, err := os.Stat(filepath.Join(webhookCertDir, "tls.crt")); err == nil {
+		mgr.GetWebhookServer().CertDir = webhookCertDir
+		mgr.GetWebhookServer().Register("/mutate-v1-pod", &webhook.Admission{
+			Handler: &controllers.SidecarInjector{
+				Client:           mgr.GetClient(),
+				Log:              ctrl.Log.WithName("webhooks").WithName("Sidecar"),
+				DefaultResources: resources["sidecar"],
+			},
+		})
+		mgr.GetWebhookServer().Register("/mutate-thanos-orangesys-io-v1beta1-querier", &webhook.Admission{
+			Handler: &controllers.QuerierDefaulter{},
+		})
+		mgr.GetWebhookServer().Register("/mutate-thanos-orangesys-io-v1beta1-store", &webhook.Admission{
+			Handler: &controllers.StoreDefaulter{},
+		})
+		mgr.GetWebhookServer().Register("/mutate-thanos-orangesys-io-v1beta1-receiver", &webhook.Admission{
+			Handler: &controllers.ReceiverDefaulter{},
+		})
+		mgr.GetWebhookServer().Register("/mutate-thanos-orangesys-io-v1beta1-compactor", &webhook.Admission{
+			Handler: &controllers.CompactorDefaulter{},
+		})
+		mgr.GetWebhookServer().Register("/mutate-thanos-orangesys-io-v1beta1-ruler", &webhook.Admission{
+			Handler: &controllers.RulerDefaulter{},
+		})
+		mgr.GetWebhookServer().Register("/mutate-thanos-orangesys-io-v1beta1-sidecar", &webhook.Admission{
+			Handler: &controllers.SidecarDefaulter{},
+		})
+		mgr.GetWebhookServer().Register("/validate-thanos-orangesys-io-v1beta1-querier", &webhook.Admission{
+			Handler: &controllers.QuerierValidator{},
+		})
+		mgr.GetWebhookServer().Register("/validate-thanos-orangesys-io-v1beta1-store", &webhook.Admission{
+			Handler: &controllers.StoreValidator{},
+		})
+		mgr.GetWebhookServer().Register("/validate-thanos-orangesys-io-v1beta1-receiver", &webhook.Admission{
+			Handler: &controllers.ReceiverValidator{},
+		})
+		mgr.GetWebhookServer().Register("/validate-thanos-orangesys-io-v1beta1-compactor", &webhook.Admission{
+			Handler: &controllers.CompactorValidator{},
+		})
+		mgr.GetWebhookServer().Register("/validate-thanos-orangesys-io-v1beta1-ruler", &webhook.Admission{
+			Handler: &controllers.RulerValidator{},
+		})
+		mgr.GetWebhookServer().Register("/validate-thanos-orangesys-io-v1beta1-sidecar", &webhook.Admission{
+			Handler: &controllers.SidecarValidator{},
+		})
+	} else {
+		setupLog.Info("not serving the admission webhooks, no serving certificate found", "dir", webhookCertDir)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")